@@ -2,22 +2,39 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	chatRepo "messanger/internal/chat/repository"
 	chatHTTP "messanger/internal/chat/transport/http"
 	chatUC "messanger/internal/chat/usecase"
+	"messanger/internal/gateway"
+	gatewayWS "messanger/internal/gateway/transport/ws"
+	"messanger/internal/lib/eventbus"
+	"messanger/internal/lib/jwt"
 	"messanger/internal/lib/logger/handlers/slogpretty"
+	"messanger/internal/lib/logger/httplog"
+	"messanger/internal/lib/notifier"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/lib/throttle"
+	msgRepo "messanger/internal/message/repository"
+	msgHTTP "messanger/internal/message/transport/http"
+	msgUC "messanger/internal/message/usecase"
+	updateRepo "messanger/internal/update/repository"
+	updateHTTP "messanger/internal/update/transport/http"
+	updateUC "messanger/internal/update/usecase"
 	userRepo "messanger/internal/user/repository"
 	userHTTP "messanger/internal/user/transport/http"
 	userUC "messanger/internal/user/usecase"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -36,6 +53,50 @@ func main() {
 		DATABASE_URL = os.Getenv("DATABASE_URL")
 		JWT_SECRET   = os.Getenv("JWT_SECRET")
 		SERVER_ADDR  = os.Getenv("SERVER_ADDR")
+		EVENT_BUS    = os.Getenv("EVENT_BUS")
+
+		// WS_ALLOWED_ORIGINS is a comma-separated list of origins, such as
+		// https://app.example.com, whose pages may open a websocket besides
+		// those served from the API host.
+		WS_ALLOWED_ORIGINS = listEnv("WS_ALLOWED_ORIGINS")
+
+		// THROTTLE_STORE is "memory" for a single instance, attempts are
+		// kept in Postgres otherwise.
+		THROTTLE_STORE = os.Getenv("THROTTLE_STORE")
+
+		// JWT_KEYS is a comma-separated list of kid=path to key files,
+		// JWT_SIGNING_KID names the one that signs new tokens.
+		JWT_SECRET_KID  = os.Getenv("JWT_SECRET_KID")
+		JWT_KEYS        = os.Getenv("JWT_KEYS")
+		JWT_SIGNING_KID = os.Getenv("JWT_SIGNING_KID")
+
+		ACCESS_TOKEN_TTL  = durationEnv("ACCESS_TOKEN_TTL", time.Minute*15)
+		REFRESH_TOKEN_TTL = durationEnv("REFRESH_TOKEN_TTL", time.Hour*24*30)
+
+		PASSWORD_RESET_TTL = durationEnv("PASSWORD_RESET_TTL", time.Hour)
+		PASSWORD_RESET_URL = os.Getenv("PASSWORD_RESET_URL")
+
+		// Password resets are sent to an email once it's verified.
+		EMAIL_VERIFY_TTL = durationEnv("EMAIL_VERIFY_TTL", time.Hour*24)
+		EMAIL_VERIFY_URL = os.Getenv("EMAIL_VERIFY_URL")
+
+		// ACCOUNT_DELETION_GRACE is how long a deleted account can be
+		// restored before its personal data is anonymized.
+		ACCOUNT_DELETION_GRACE = durationEnv("ACCOUNT_DELETION_GRACE", time.Hour*24*30)
+
+		// MESSAGE_EDIT_WINDOW is how long a message can be edited after it's
+		// sent, 0 for no limit.
+		MESSAGE_EDIT_WINDOW = durationEnv("MESSAGE_EDIT_WINDOW", time.Hour*48)
+
+		// MESSAGE_DELETE_WINDOW is how long the author can delete a message
+		// for everyone, 0 for no limit. Moderators always can.
+		MESSAGE_DELETE_WINDOW = durationEnv("MESSAGE_DELETE_WINDOW", time.Hour*48)
+
+		// PASSWORD_HASH is "bcrypt" or "argon2id". Stored hashes of the
+		// other kind keep working and are upgraded on the next login.
+		PASSWORD_HASH       = os.Getenv("PASSWORD_HASH")
+		BCRYPT_COST         = intEnv("BCRYPT_COST", bcrypt.DefaultCost)
+		PASSWORD_MIN_LENGTH = intEnv("PASSWORD_MIN_LENGTH", 8)
 	)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -44,7 +105,67 @@ func main() {
 	log := setupLogger(envLocal, os.Stdout)
 
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(httplog.Logger("token"))
+
+	sessionStorage, err := userRepo.New(ctx, DATABASE_URL)
+	if err != nil {
+		panic(err)
+	}
+
+	if JWT_SECRET_KID == "" {
+		JWT_SECRET_KID = "default"
+	}
+
+	keyring, err := jwt.LoadKeyring(JWT_SECRET, JWT_SECRET_KID, JWT_KEYS, JWT_SIGNING_KID)
+	if err != nil {
+		panic(err)
+	}
+
+	r.Get("/.well-known/jwks.json", userHTTP.JWKS(keyring))
+
+	if PASSWORD_HASH == "" {
+		PASSWORD_HASH = passwd.AlgBcrypt
+	}
+
+	hasher, err := passwd.NewHasher(PASSWORD_HASH, BCRYPT_COST, passwd.DefaultArgon2Params)
+	if err != nil {
+		panic(err)
+	}
+
+	policy := passwd.NewPolicy(PASSWORD_MIN_LENGTH, PASSWORD_HASH)
+
+	limits := setupLimits(ctx, log, THROTTLE_STORE, DATABASE_URL)
+
+	sessions := userUC.NewSessions(log, sessionStorage, time.Second*30)
+	authMiddleware := userHTTP.AuthMiddleware(keyring, sessions)
+
+	gatewayStorage, err := chatRepo.New(ctx, DATABASE_URL)
+	if err != nil {
+		panic(err)
+	}
+
+	bus := setupEventBus(ctx, log, EVENT_BUS, DATABASE_URL)
+
+	hub := gateway.NewHub(log, gatewayStorage)
+	bus.Subscribe(hub.Handle)
+	r.Get("/ws", gatewayWS.New(log, hub, keyring, sessions, WS_ALLOWED_ORIGINS).Connect)
+
+	journalStorage, err := updateRepo.New(ctx, DATABASE_URL)
+	if err != nil {
+		panic(err)
+	}
+
+	journal := updateUC.NewJournal(log, journalStorage, bus)
+
+	updateStorage, err := updateRepo.New(ctx, DATABASE_URL)
+	if err != nil {
+		panic(err)
+	}
+
+	updates := updateUC.NewUpdates(log, updateStorage, time.Hour*24*30)
+	go updates.RunRetention(ctx)
+
+	r.With(authMiddleware).Get("/updates", updateHTTP.New(log, updates).Since)
 
 	r.Route("/user", func(r chi.Router) {
 		storage, err := userRepo.New(ctx, DATABASE_URL)
@@ -52,15 +173,47 @@ func main() {
 			panic(err)
 		}
 
-		auth := userUC.NewAuth(log, storage, JWT_SECRET, time.Hour*24)
-		profile := userUC.NewProfile(log, storage)
-		handler := userHTTP.NewUserHandler(log, auth, profile)
+		auth := userUC.NewAuth(log, storage, storage, storage, sessions, keyring, hasher, policy, ACCESS_TOKEN_TTL, REFRESH_TOKEN_TTL)
+		profile := userUC.NewProfile(log, storage, sessions, hasher, ACCOUNT_DELETION_GRACE)
+		go profile.RunPurge(ctx)
+
+		notify := setupNotifier(log)
+		password := userUC.NewPassword(log, storage, storage, sessions, notify, hasher, policy, PASSWORD_RESET_TTL, PASSWORD_RESET_URL)
+		email := userUC.NewEmail(log, storage, storage, notify, hasher, EMAIL_VERIFY_TTL, EMAIL_VERIFY_URL)
+		mfa := userUC.NewMFA(log, storage, storage, hasher)
+		handler := userHTTP.NewUserHandler(log, auth, profile, sessions, password, email, mfa, limits)
 
 		r.Post("/register", handler.Register)
 		r.Post("/login", handler.Login)
+		r.Post("/login/mfa", handler.LoginMFA)
+		r.Post("/refresh", handler.Refresh)
+		r.Post("/logout", handler.Logout)
+		r.Post("/restore", handler.Restore)
+
+		r.Post("/password/reset", handler.RequestReset)
+		r.Post("/password/reset/confirm", handler.ResetPassword)
+		r.Post("/email/verify", handler.VerifyEmail)
 
-		// todo: get id from Authorization token(not pattern)
-		r.With(userHTTP.AuthMiddleware).Delete("/", handler.Delete)
+		r.With(authMiddleware).Delete("/", handler.Delete)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware)
+
+			r.Put("/password", handler.ChangePassword)
+			r.Post("/email", handler.SetEmail)
+
+			r.Post("/2fa", handler.EnrollMFA)
+			r.Post("/2fa/confirm", handler.ConfirmMFA)
+			r.Delete("/2fa", handler.DisableMFA)
+
+			r.Get("/me", handler.Me)
+			r.Patch("/me", handler.UpdateMe)
+			r.Get("/{id}", handler.Get)
+
+			r.Get("/sessions", handler.ListSessions)
+			r.Delete("/sessions", handler.RevokeOtherSessions)
+			r.Delete("/sessions/{id}", handler.RevokeSession)
+		})
 	})
 
 	r.Route("/chat", func(r chi.Router) {
@@ -69,17 +222,58 @@ func main() {
 			panic(err)
 		}
 
-		r.Use(userHTTP.AuthMiddleware)
+		r.Use(authMiddleware)
 
-		chatUc := chatUC.NewChat(log, storage)
+		chatUc := chatUC.NewChat(log, storage, journal)
 		handler := chatHTTP.New(log, chatUc)
 
+		r.Get("/", handler.List)
+
 		r.Post("/channel", handler.CreateChannel)
 		r.Post("/group", handler.CreateGroup)
 		r.Post("/private", handler.CreatePrivate)
 
 		r.Post("/join", handler.Join)
 		r.Post("/leave", handler.Leave)
+
+		r.Patch("/{id}", handler.Update)
+		r.Delete("/{id}", handler.Delete)
+		r.Post("/{id}/members", handler.Invite)
+		r.Patch("/{id}/members/{userID}", handler.SetRole)
+		r.Delete("/{id}/members/{userID}", handler.Kick)
+
+		r.Get("/{id}/bans", handler.ListBans)
+		r.Put("/{id}/bans/{userID}", handler.Ban)
+		r.Delete("/{id}/bans/{userID}", handler.Unban)
+
+		msgStorage, err := msgRepo.New(ctx, DATABASE_URL)
+		if err != nil {
+			panic(err)
+		}
+
+		messageUc := msgUC.NewMessage(log, msgStorage, journal, MESSAGE_EDIT_WINDOW, MESSAGE_DELETE_WINDOW)
+		msgHandler := msgHTTP.New(log, messageUc)
+
+		r.Post("/{id}/read", msgHandler.MarkRead)
+		r.Get("/{id}/unread", msgHandler.ReadState)
+		r.Get("/{id}/pins", msgHandler.Pins)
+		r.Post("/{id}/pins", msgHandler.Pin)
+		r.Delete("/{id}/pins/{msgID}", msgHandler.Unpin)
+
+		r.Route("/{id}/messages", func(r chi.Router) {
+			r.Post("/", msgHandler.Send)
+			r.Get("/", msgHandler.History)
+			r.Post("/delete", msgHandler.DeleteMany)
+			r.Patch("/{msgID}", msgHandler.Edit)
+			r.Delete("/{msgID}", msgHandler.Delete)
+			r.Get("/{msgID}/revisions", msgHandler.Revisions)
+			r.Get("/{msgID}/read-by", msgHandler.ReadBy)
+			r.Get("/{msgID}/thread", msgHandler.Thread)
+			r.Post("/{msgID}/thread", msgHandler.Reply)
+			r.Post("/{msgID}/thread/read", msgHandler.MarkThreadRead)
+			r.Post("/{msgID}/reactions", msgHandler.React)
+			r.Delete("/{msgID}/reactions", msgHandler.Unreact)
+		})
 	})
 
 	log.Info("trying to start server...", slog.String("addr", SERVER_ADDR))
@@ -88,6 +282,122 @@ func main() {
 	}
 }
 
+// durationEnv parses a duration like "15m" from the environment.
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", key, err))
+	}
+
+	return d
+}
+
+// intEnv parses an integer from the environment.
+func intEnv(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", key, err))
+	}
+
+	return n
+}
+
+// listEnv splits a comma-separated list from the environment.
+func listEnv(key string) []string {
+	var list []string
+
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// setupNotifier sends through SMTP when NOTIFIER is "smtp". "file" is for
+// local development only, messages with their tokens go to NOTIFIER_FILE, or
+// to the log if it isn't set. Anything else fails at startup.
+func setupNotifier(log *slog.Logger) notifier.Notifier {
+	switch kind := os.Getenv("NOTIFIER"); kind {
+	case "smtp":
+		n, err := notifier.NewSMTP(
+			os.Getenv("SMTP_ADDR"),
+			os.Getenv("SMTP_USER"),
+			os.Getenv("SMTP_PASSWORD"),
+			os.Getenv("SMTP_FROM"),
+		)
+		if err != nil {
+			panic(err)
+		}
+
+		return n
+	case "file":
+		log.Warn("notifications are written to a file or the log, don't use it in production")
+		return notifier.NewFile(log, os.Getenv("NOTIFIER_FILE"))
+	default:
+		panic(fmt.Errorf("NOTIFIER must be smtp or file, got %q", kind))
+	}
+}
+
+// setupLimits throttles logins per login and per IP, and registrations per
+// IP.
+func setupLimits(ctx context.Context, log *slog.Logger, kind, dbURL string) userHTTP.Limits {
+	var store throttle.Store
+
+	if kind == "memory" {
+		store = throttle.NewMemory()
+	} else {
+		pg, err := throttle.NewPostgres(ctx, dbURL)
+		if err != nil {
+			panic(err)
+		}
+
+		store = pg
+	}
+
+	go throttle.RunCleanup(ctx, log, store, time.Hour*24)
+
+	return userHTTP.Limits{
+		Login: throttle.New(log, store, "login", throttle.Policy{
+			Free: 5, Base: time.Second, Max: time.Minute * 15, Window: time.Hour,
+		}),
+		LoginIP: throttle.New(log, store, "login_ip", throttle.Policy{
+			Free: 20, Base: time.Second, Max: time.Minute * 15, Window: time.Hour,
+		}),
+		RegisterIP: throttle.New(log, store, "register_ip", throttle.Policy{
+			Free: 5, Base: time.Minute, Max: time.Hour * 6, Window: time.Hour * 24,
+		}),
+	}
+}
+
+// setupEventBus returns the in-process bus for "inproc" and the Postgres
+// LISTEN/NOTIFY bus otherwise, which is required to run several replicas.
+func setupEventBus(ctx context.Context, log *slog.Logger, kind, dbURL string) eventbus.Bus {
+	if kind == "inproc" {
+		return eventbus.NewInProc()
+	}
+
+	bus, err := eventbus.NewPostgres(ctx, log, dbURL)
+	if err != nil {
+		panic(err)
+	}
+
+	go bus.Run(ctx)
+
+	return bus
+}
+
 func setupLogger(env string, out io.Writer) *slog.Logger {
 	var log *slog.Logger
 
@@ -2,30 +2,86 @@ package chat
 
 import "time"
 
+const (
+	TypePrivate = "private"
+	TypeGroup   = "group"
+	TypeChannel = "channel"
+
+	// MaxAllowedReactions is how many emoji a channel can restrict reactions
+	// to.
+	MaxAllowedReactions = 50
+)
+
 type Chat struct {
-	ID        uint64
-	Type      string
-	Address   string
+	ID          uint64
+	Type        string
+	Address     string
+	Title       string
+	Description string
+	// Avatar is a reference to the image, the chat doesn't store the image.
+	Avatar    string
 	CreatedAt time.Time
-	// ChatMembers
-}
-
-// type ChatMembers struct {
-// 	Role          string
-// 	ChatID        uint64
-// 	UserID        []uint64
-// 	JoinedAt      time.Time
-// 	IsBanned      bool
-// 	LastReadMsgID *uint64
-// }
-
-// func NewChatMember(role string, chatID uint64, userID []uint64) ChatMembers {
-// 	return ChatMembers{
-// 		Role:          role,
-// 		ChatID:        chatID,
-// 		UserID:        userID,
-// 		JoinedAt:      time.Now(),
-// 		IsBanned:      false,
-// 		LastReadMsgID: nil,
-// 	}
-// }
+	// AllowedReactions restricts the reactions in a channel, nil allows any.
+	AllowedReactions []string
+}
+
+// InfoUpdate is a partial update of a chat's info, nil fields are left as
+// they are. An empty Address removes the public address, an empty
+// AllowedReactions allows any reaction again.
+type InfoUpdate struct {
+	Title            *string
+	Description      *string
+	Avatar           *string
+	Address          *string
+	AllowedReactions *[]string
+}
+
+func (u InfoUpdate) IsEmpty() bool {
+	return u.Title == nil && u.Description == nil && u.Avatar == nil && u.Address == nil && u.AllowedReactions == nil
+}
+
+// Member is a chat_members row. A banned user keeps the row with IsBanned
+// set but is not a member of the chat anymore.
+type Member struct {
+	Role          Role
+	ChatID        uint64
+	UserID        uint64
+	JoinedAt      time.Time
+	IsBanned      bool
+	BannedUntil   *time.Time
+	LastReadMsgID *uint64
+}
+
+// BanActive reports whether the member is banned and the ban hasn't expired.
+func (m Member) BanActive(now time.Time) bool {
+	return m.IsBanned && (m.BannedUntil == nil || m.BannedUntil.After(now))
+}
+
+func NewMember(role Role, chatID uint64, userID uint64) Member {
+	return Member{
+		Role:     role,
+		ChatID:   chatID,
+		UserID:   userID,
+		JoinedAt: time.Now(),
+	}
+}
+
+type Ban struct {
+	ChatID    uint64
+	UserID    uint64
+	Reason    string
+	BannedBy  uint64
+	BannedAt  time.Time
+	ExpiresAt *time.Time
+}
+
+func NewBan(chatID, userID, bannedBy uint64, reason string, expiresAt *time.Time) Ban {
+	return Ban{
+		ChatID:    chatID,
+		UserID:    userID,
+		Reason:    reason,
+		BannedBy:  bannedBy,
+		BannedAt:  time.Now(),
+		ExpiresAt: expiresAt,
+	}
+}
@@ -4,4 +4,9 @@ import "errors"
 
 var (
 	ErrChatAlreadyExist = errors.New("chat already exist")
+	ErrChatNotFound     = errors.New("chat not found")
+	ErrMemberNotFound   = errors.New("member not found")
+	ErrAlreadyMember    = errors.New("user is already a member of the chat")
+	ErrPeerNotFound     = errors.New("user not found")
+	ErrBanNotFound      = errors.New("ban not found")
 )
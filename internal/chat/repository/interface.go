@@ -9,20 +9,49 @@ type ChatRepo interface {
 	ChatReader
 	ChatWriter
 	ChatUserActions
+	ChatModeration
+
+	// InTx runs fn in one transaction, with the events it publishes.
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type ChatReader interface {
 	GetByID(ctx context.Context, id uint64) (chat.Chat, error)
 	GetByAddress(ctx context.Context, address string) (chat.Chat, error)
+	ListUserChatIDs(ctx context.Context, userID uint64) ([]uint64, error)
+	// ListUserChats returns the user's chats ordered by latest activity,
+	// starting after the cursor when it is not nil.
+	ListUserChats(ctx context.Context, userID uint64, after *chat.ListCursor, limit int) ([]chat.Summary, error)
 }
 
 type ChatWriter interface {
-	Create(ctx context.Context, chat chat.Chat) (uint64, error)
-	// Update(ctx context.Context, newChat chat.Chat) error
+	// Create inserts the chat together with its initial members atomically.
+	Create(ctx context.Context, chat chat.Chat, members ...chat.Member) (uint64, error)
+	// GetOrCreatePrivate returns the chat and the users that have been added to it.
+	GetOrCreatePrivate(ctx context.Context, userID uint64, peerID uint64) (uint64, []uint64, error)
+	// Update applies a partial update of the chat's info and returns the
+	// updated chat.
+	Update(ctx context.Context, chatID uint64, upd chat.InfoUpdate) (chat.Chat, error)
 	Delete(ctx context.Context, id uint64) error
 }
 
 type ChatUserActions interface {
-	Join(ctx context.Context, role string, userID uint64, chatID uint64) error
+	// Join adds the user or re-admits them once their ban has expired.
+	Join(ctx context.Context, role chat.Role, userID uint64, chatID uint64) error
 	Leave(ctx context.Context, userID uint64, chatID uint64) error
+	// GetMember also returns rows of banned users.
+	GetMember(ctx context.Context, chatID uint64, userID uint64) (chat.Member, error)
+	ListMemberIDs(ctx context.Context, chatID uint64) ([]uint64, error)
+	SetRole(ctx context.Context, chatID uint64, userID uint64, role chat.Role) error
+	// TransferOwnership makes newOwnerID the owner and demotes the current
+	// owner to admin in one transaction.
+	TransferOwnership(ctx context.Context, chatID uint64, ownerID uint64, newOwnerID uint64) error
+}
+
+// ChatModeration records every action in chat_moderation_log.
+type ChatModeration interface {
+	Kick(ctx context.Context, chatID uint64, userID uint64, actorID uint64) error
+	Ban(ctx context.Context, ban chat.Ban) error
+	Unban(ctx context.Context, chatID uint64, userID uint64, actorID uint64) error
+	ListBans(ctx context.Context, chatID uint64) ([]chat.Ban, error)
 }
@@ -2,59 +2,254 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"messanger/internal/chat"
+	"messanger/internal/lib/pgtx"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
 )
 
 type Storage struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
 func New(ctx context.Context, dbURL string) (*Storage, error) {
 	const op = "chat.repository.postgres.New"
 
-	conn, err := pgx.Connect(ctx, dbURL)
+	pool, err := pgxpool.New(ctx, dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: conn}, nil
+	return &Storage{db: pool}, nil
 }
 
 func (s *Storage) Close(ctx context.Context) error {
-	return s.db.Close(ctx)
+	s.db.Close()
+
+	return nil
+}
+
+// InTx runs fn in a transaction. Repositories called with the context fn
+// gets write in that transaction.
+func (s *Storage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return pgtx.Run(ctx, s.db, fn)
+}
+
+// conn is the transaction of ctx if there is one, or the pool.
+func (s *Storage) conn(ctx context.Context) pgtx.Conn {
+	return pgtx.From(ctx, s.db)
 }
 
-func (s *Storage) Create(ctx context.Context, chat chat.Chat) (uint64, error) {
+func (s *Storage) Create(ctx context.Context, chat chat.Chat, members ...chat.Member) (uint64, error) {
 	const op = "chat.repository.postgres.Create"
 
-	sql := `INSERT INTO chats(type, address) VALUES(@type, @address) RETURNING id;`
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO chats(type, address, title, description, avatar)
+		VALUES(@type, NULLIF(@address, ''), @title, @description, @avatar)
+		RETURNING id;`
 	args := pgx.NamedArgs{
-		"type":    chat.Type,
-		"address": chat.Address,
+		"type":        chat.Type,
+		"address":     chat.Address,
+		"title":       chat.Title,
+		"description": chat.Description,
+		"avatar":      chat.Avatar,
 	}
 
 	var chatID uint64
 
-	if err := s.db.QueryRow(ctx, sql, args).Scan(&chatID); err != nil {
+	if err := tx.QueryRow(ctx, sql, args).Scan(&chatID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, fmt.Errorf("%s: %w", op, ErrChatAlreadyExist)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, member := range members {
+		sql := `INSERT INTO chat_members(role, chat_id, user_id) VALUES(@role, @chat_id, @user_id)`
+		args := pgx.NamedArgs{
+			"role":    member.Role,
+			"chat_id": chatID,
+			"user_id": member.UserID,
+		}
+
+		if _, err := tx.Exec(ctx, sql, args); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return chatID, nil
 }
 
-// func (s *Storage) Update(ctx context.Context, newChat chat.Chat) error {
-// 	const op = "chat.repository.postgres.Update"
+func (s *Storage) Update(ctx context.Context, chatID uint64, upd chat.InfoUpdate) (chat.Chat, error) {
+	const op = "chat.repository.postgres.Update"
+
+	sql := `UPDATE chats SET
+			title = COALESCE(@title, title),
+			description = COALESCE(@description, description),
+			avatar = COALESCE(@avatar, avatar),
+			address = CASE WHEN @set_address THEN NULLIF(@address, '') ELSE address END,
+			allowed_reactions = CASE WHEN @set_reactions THEN @reactions ELSE allowed_reactions END
+		WHERE id = @id
+		RETURNING id, type, COALESCE(address, ''), title, description, avatar, created_at, allowed_reactions`
+	args := pgx.NamedArgs{
+		"id":            chatID,
+		"title":         upd.Title,
+		"description":   upd.Description,
+		"avatar":        upd.Avatar,
+		"set_address":   upd.Address != nil,
+		"address":       upd.Address,
+		"set_reactions": upd.AllowedReactions != nil,
+		"reactions":     nil,
+	}
+
+	if upd.AllowedReactions != nil && len(*upd.AllowedReactions) > 0 {
+		args["reactions"] = *upd.AllowedReactions
+	}
+
+	var cht chat.Chat
+
+	err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(
+		&cht.ID,
+		&cht.Type,
+		&cht.Address,
+		&cht.Title,
+		&cht.Description,
+		&cht.Avatar,
+		&cht.CreatedAt,
+		&cht.AllowedReactions,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return chat.Chat{}, fmt.Errorf("%s: %w", op, ErrChatNotFound)
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return chat.Chat{}, fmt.Errorf("%s: %w", op, ErrChatAlreadyExist)
+		}
+
+		return chat.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cht, nil
+}
+
+// GetOrCreatePrivate returns the private chat between two users, creating it
+// with both memberships if it doesn't exist yet. Memberships of an existing
+// chat are restored if one of the users has left it. The users whose
+// membership has been created are returned.
+func (s *Storage) GetOrCreatePrivate(ctx context.Context, userID uint64, peerID uint64) (uint64, []uint64, error) {
+	const op = "chat.repository.postgres.GetOrCreatePrivate"
+
+	low, high := min(userID, peerID), max(userID, peerID)
+
+	chatID, joined, err := s.getOrCreatePrivate(ctx, low, high)
+	if errors.Is(err, errPrivateChatRace) {
+		// A concurrent request has created the chat, it's visible now.
+		chatID, joined, err = s.getOrCreatePrivate(ctx, low, high)
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return 0, nil, fmt.Errorf("%s: %w", op, ErrPeerNotFound)
+		}
+
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chatID, joined, nil
+}
+
+var errPrivateChatRace = errors.New("private chat created concurrently")
+
+func (s *Storage) getOrCreatePrivate(ctx context.Context, low, high uint64) (uint64, []uint64, error) {
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var chatID uint64
+
+	sql := `SELECT chat_id FROM private_chats WHERE user_low_id = @low AND user_high_id = @high`
+	args := pgx.NamedArgs{
+		"low":  low,
+		"high": high,
+	}
+
+	err = tx.QueryRow(ctx, sql, args).Scan(&chatID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		sql := `INSERT INTO chats(type) VALUES(@type) RETURNING id`
+		if err := tx.QueryRow(ctx, sql, pgx.NamedArgs{"type": chat.TypePrivate}).Scan(&chatID); err != nil {
+			return 0, nil, err
+		}
+
+		sql = `INSERT INTO private_chats(chat_id, user_low_id, user_high_id) VALUES(@chat_id, @low, @high)
+			ON CONFLICT (user_low_id, user_high_id) DO NOTHING`
+		args["chat_id"] = chatID
+
+		tag, err := tx.Exec(ctx, sql, args)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return 0, nil, errPrivateChatRace
+		}
+	case err != nil:
+		return 0, nil, err
+	}
+
+	sql = `INSERT INTO chat_members(role, chat_id, user_id) VALUES(@role, @chat_id, @user_id)
+		ON CONFLICT (chat_id, user_id) DO NOTHING`
+
+	var joined []uint64
+
+	for _, userID := range []uint64{low, high} {
+		args := pgx.NamedArgs{
+			"role":    chat.RoleMember,
+			"chat_id": chatID,
+			"user_id": userID,
+		}
+
+		tag, err := tx.Exec(ctx, sql, args)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if tag.RowsAffected() > 0 {
+			joined = append(joined, userID)
+		}
+	}
 
-// 	sql := `UPDATE chats SET `
-// 	args := pgx.NamedArgs{
-// 		"id": id,
-// 	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, nil, err
+	}
 
-// 	return nil
-// }
+	return chatID, joined, nil
+}
 
 func (s *Storage) Delete(ctx context.Context, id uint64) error {
 	const op = "chat.repository.postgres.Delete"
@@ -69,7 +264,7 @@ func (s *Storage) Delete(ctx context.Context, id uint64) error {
 		"id": id,
 	}
 
-	_, err = s.db.Exec(ctx, sql, args)
+	_, err = s.conn(ctx).Exec(ctx, sql, args)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -80,20 +275,29 @@ func (s *Storage) Delete(ctx context.Context, id uint64) error {
 func (s *Storage) GetByID(ctx context.Context, id uint64) (chat.Chat, error) {
 	const op = "chat.repository.postgres.GetByID"
 
-	sql := `SELECT id, type, address, created_at FROM chats WHERE id = @id`
+	sql := `SELECT id, type, COALESCE(address, ''), title, description, avatar, created_at, allowed_reactions
+		FROM chats WHERE id = @id`
 	args := pgx.NamedArgs{
 		"id": id,
 	}
 
 	var cht chat.Chat
 
-	err := s.db.QueryRow(ctx, sql, args).Scan(
+	err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(
 		&cht.ID,
 		&cht.Type,
 		&cht.Address,
+		&cht.Title,
+		&cht.Description,
+		&cht.Avatar,
 		&cht.CreatedAt,
+		&cht.AllowedReactions,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return chat.Chat{}, fmt.Errorf("%s: %w", op, ErrChatNotFound)
+		}
+
 		return chat.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -103,55 +307,527 @@ func (s *Storage) GetByID(ctx context.Context, id uint64) (chat.Chat, error) {
 func (s *Storage) GetByAddress(ctx context.Context, address string) (chat.Chat, error) {
 	const op = "chat.repository.postgres.GetByAddress"
 
-	sql := `SELECT id, type, address, created_at FROM chats WHERE address = @address`
+	sql := `SELECT id, type, address, title, description, avatar, created_at, allowed_reactions FROM chats
+		WHERE address = @address AND type <> 'private'`
 	args := pgx.NamedArgs{
 		"address": address,
 	}
 
 	var cht chat.Chat
 
-	err := s.db.QueryRow(ctx, sql, args).Scan(
+	err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(
 		&cht.ID,
 		&cht.Type,
 		&cht.Address,
+		&cht.Title,
+		&cht.Description,
+		&cht.Avatar,
 		&cht.CreatedAt,
+		&cht.AllowedReactions,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return chat.Chat{}, fmt.Errorf("%s: %w", op, ErrChatNotFound)
+		}
+
 		return chat.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return cht, nil
 }
 
-func (s *Storage) Join(ctx context.Context, role string, userID uint64, chatID uint64) error {
+func (s *Storage) ListUserChatIDs(ctx context.Context, userID uint64) ([]uint64, error) {
+	const op = "chat.repository.postgres.ListUserChatIDs"
+
+	sql := `SELECT chat_id FROM chat_members WHERE user_id = @user_id AND NOT is_banned`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	chatIDs, err := pgx.CollectRows(rows, pgx.RowTo[uint64])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chatIDs, nil
+}
+
+// ListUserChats pages over idx_chat_members_user_id by chats.last_activity_at
+// and only then looks up the last message, its author and the unread count
+// for the chats of the page.
+func (s *Storage) ListUserChats(ctx context.Context, userID uint64, after *chat.ListCursor, limit int) ([]chat.Summary, error) {
+	const op = "chat.repository.postgres.ListUserChats"
+
+	sql := `WITH page AS (
+			SELECT c.id, c.type, c.address, c.title, c.avatar, c.created_at, c.last_msg_id, c.last_activity_at,
+				cm.role, cm.user_id, COALESCE(cm.last_read_msg_id, 0) AS last_read_msg_id
+			FROM chat_members cm
+			JOIN chats c ON c.id = cm.chat_id
+			WHERE cm.user_id = @user_id AND NOT cm.is_banned
+				AND (NOT @has_cursor OR (c.last_activity_at, c.id) < (@cursor_at, @cursor_id))
+			ORDER BY c.last_activity_at DESC, c.id DESC
+			LIMIT @limit
+		)
+		SELECT p.id, p.type, COALESCE(p.address, ''), p.title, p.avatar, p.created_at,
+			COALESCE(
+				(SELECT u.name FROM private_chats pc
+					JOIN users u ON u.id = CASE WHEN pc.user_low_id = p.user_id THEN pc.user_high_id ELSE pc.user_low_id END
+					WHERE pc.chat_id = p.id),
+				NULLIF(p.title, ''),
+				p.address,
+				''
+			),
+			p.role, p.last_read_msg_id, p.last_activity_at,
+			(SELECT count(*) FROM msgs um
+				WHERE um.chat_id = p.id AND um.id > p.last_read_msg_id AND um.author_user_id <> p.user_id
+					AND um.thread_root_id IS NULL
+					AND um.service IS NULL
+					AND um.deleted_at IS NULL
+					AND NOT EXISTS (SELECT 1 FROM msg_hidden h WHERE h.msg_id = um.id AND h.user_id = p.user_id)),
+			m.id, m.author_user_id, author.name, left(m.text, @snippet), m.created_at, m.deleted_at
+		FROM page p
+		LEFT JOIN LATERAL (
+			-- The last message unless the user has hidden it, then the
+			-- latest one before it they haven't.
+			SELECT lm.id, lm.author_user_id, lm.text, lm.created_at, lm.deleted_at FROM msgs lm
+			WHERE lm.chat_id = p.id AND lm.id <= p.last_msg_id
+				AND lm.thread_root_id IS NULL
+				AND NOT EXISTS (SELECT 1 FROM msg_hidden h WHERE h.msg_id = lm.id AND h.user_id = p.user_id)
+			ORDER BY lm.id DESC
+			LIMIT 1
+		) m ON true
+		LEFT JOIN users author ON author.id = m.author_user_id
+		ORDER BY p.last_activity_at DESC, p.id DESC`
+	args := pgx.NamedArgs{
+		"user_id":    userID,
+		"has_cursor": after != nil,
+		"cursor_at":  nil,
+		"cursor_id":  nil,
+		"limit":      limit,
+		"snippet":    chat.SnippetLength,
+	}
+
+	if after != nil {
+		args["cursor_at"] = after.LastActivityAt
+		args["cursor_id"] = after.ChatID
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	summaries := make([]chat.Summary, 0, limit)
+
+	for rows.Next() {
+		var (
+			sum        chat.Summary
+			msgID      *uint64
+			authorID   *uint64
+			authorName *string
+			snippet    *string
+			msgAt      *time.Time
+			deletedAt  *time.Time
+		)
+
+		err := rows.Scan(
+			&sum.Chat.ID,
+			&sum.Chat.Type,
+			&sum.Chat.Address,
+			&sum.Chat.Title,
+			&sum.Chat.Avatar,
+			&sum.Chat.CreatedAt,
+			&sum.Title,
+			&sum.Role,
+			&sum.LastReadMsgID,
+			&sum.LastActivityAt,
+			&sum.UnreadCount,
+			&msgID,
+			&authorID,
+			&authorName,
+			&snippet,
+			&msgAt,
+			&deletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if msgID != nil {
+			sum.LastMessage = &chat.MessagePreview{
+				ID:           *msgID,
+				AuthorUserID: *authorID,
+				AuthorName:   *authorName,
+				Snippet:      *snippet,
+				CreatedAt:    *msgAt,
+				DeletedAt:    deletedAt,
+			}
+		}
+
+		summaries = append(summaries, sum)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return summaries, nil
+}
+
+func (s *Storage) Join(ctx context.Context, role chat.Role, userID uint64, chatID uint64) error {
 	const op = "chat.repository.postgres.Join"
 
-	sql := `INSERT INTO chat_members(role, chat_id, user_id) VALUES(@role, @chat_id, @user_id)`
+	// An expired ban row is turned back into a membership, any other
+	// existing row means the user is a member or is still banned.
+	sql := `INSERT INTO chat_members(role, chat_id, user_id) VALUES(@role, @chat_id, @user_id)
+		ON CONFLICT (chat_id, user_id) DO UPDATE SET
+			role = EXCLUDED.role,
+			joined_at = current_timestamp,
+			is_banned = FALSE,
+			ban_reason = NULL,
+			banned_by = NULL,
+			banned_at = NULL,
+			banned_until = NULL
+		WHERE chat_members.is_banned AND chat_members.banned_until <= now()`
 	args := pgx.NamedArgs{
 		"role":    role,
 		"chat_id": chatID,
 		"user_id": userID,
 	}
 
-	if _, err := s.db.Exec(ctx, sql, args); err != nil {
+	tag, err := s.conn(ctx).Exec(ctx, sql, args)
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAlreadyMember)
+	}
+
 	return nil
 }
 
 func (s *Storage) Leave(ctx context.Context, userID uint64, chatID uint64) error {
 	const op = "chat.repository.postgres.Leave"
 
-	sql := `DELETE FROM chat_members WHERE user_id = @user_id AND chat_id = @chat_id`
+	sql := `DELETE FROM chat_members WHERE user_id = @user_id AND chat_id = @chat_id AND NOT is_banned`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"chat_id": chatID,
+	}
+
+	tag, err := s.conn(ctx).Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetMember(ctx context.Context, chatID uint64, userID uint64) (chat.Member, error) {
+	const op = "chat.repository.postgres.GetMember"
+
+	sql := `SELECT role, chat_id, user_id, joined_at, is_banned, banned_until, last_read_msg_id FROM chat_members
+		WHERE chat_id = @chat_id AND user_id = @user_id`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+		"user_id": userID,
+	}
+
+	var member chat.Member
+
+	err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(
+		&member.Role,
+		&member.ChatID,
+		&member.UserID,
+		&member.JoinedAt,
+		&member.IsBanned,
+		&member.BannedUntil,
+		&member.LastReadMsgID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return chat.Member{}, fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+		}
+
+		return chat.Member{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return member, nil
+}
+
+func (s *Storage) ListMemberIDs(ctx context.Context, chatID uint64) ([]uint64, error) {
+	const op = "chat.repository.postgres.ListMemberIDs"
+
+	sql := `SELECT user_id FROM chat_members WHERE chat_id = @chat_id AND NOT is_banned`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[uint64])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userIDs, nil
+}
+
+func (s *Storage) SetRole(ctx context.Context, chatID uint64, userID uint64, role chat.Role) error {
+	const op = "chat.repository.postgres.SetRole"
+
+	sql := `UPDATE chat_members SET role = @role WHERE chat_id = @chat_id AND user_id = @user_id AND NOT is_banned`
+	args := pgx.NamedArgs{
+		"role":    role,
+		"chat_id": chatID,
+		"user_id": userID,
+	}
+
+	tag, err := s.conn(ctx).Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) TransferOwnership(ctx context.Context, chatID uint64, ownerID uint64, newOwnerID uint64) error {
+	const op = "chat.repository.postgres.TransferOwnership"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE chat_members SET role = @role WHERE chat_id = @chat_id AND user_id = @user_id AND NOT is_banned`
+
+	for _, change := range []struct {
+		userID uint64
+		role   chat.Role
+	}{
+		{userID: ownerID, role: chat.RoleAdmin},
+		{userID: newOwnerID, role: chat.RoleOwner},
+	} {
+		args := pgx.NamedArgs{
+			"role":    change.role,
+			"chat_id": chatID,
+			"user_id": change.userID,
+		}
+
+		tag, err := tx.Exec(ctx, sql, args)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Kick(ctx context.Context, chatID uint64, userID uint64, actorID uint64) error {
+	const op = "chat.repository.postgres.Kick"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `DELETE FROM chat_members WHERE user_id = @user_id AND chat_id = @chat_id AND NOT is_banned`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"chat_id": chatID,
+	}
+
+	tag, err := tx.Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+	}
+
+	if err := logModeration(ctx, tx, "kick", chatID, userID, actorID, "", nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Ban turns an existing membership into a ban or records a ban for a user
+// who isn't in the chat, so that they can't join it.
+func (s *Storage) Ban(ctx context.Context, ban chat.Ban) error {
+	const op = "chat.repository.postgres.Ban"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO chat_members(chat_id, user_id, is_banned, ban_reason, banned_by, banned_at, banned_until)
+		VALUES(@chat_id, @user_id, TRUE, NULLIF(@reason, ''), @banned_by, @banned_at, @banned_until)
+		ON CONFLICT (chat_id, user_id) DO UPDATE SET
+			role = DEFAULT,
+			is_banned = TRUE,
+			ban_reason = EXCLUDED.ban_reason,
+			banned_by = EXCLUDED.banned_by,
+			banned_at = EXCLUDED.banned_at,
+			banned_until = EXCLUDED.banned_until`
+	args := pgx.NamedArgs{
+		"chat_id":      ban.ChatID,
+		"user_id":      ban.UserID,
+		"reason":       ban.Reason,
+		"banned_by":    ban.BannedBy,
+		"banned_at":    ban.BannedAt,
+		"banned_until": ban.ExpiresAt,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf("%s: %w", op, ErrPeerNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := logModeration(ctx, tx, "ban", ban.ChatID, ban.UserID, ban.BannedBy, ban.Reason, ban.ExpiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Unban removes the ban row, the user has to join the chat again.
+func (s *Storage) Unban(ctx context.Context, chatID uint64, userID uint64, actorID uint64) error {
+	const op = "chat.repository.postgres.Unban"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `DELETE FROM chat_members WHERE user_id = @user_id AND chat_id = @chat_id AND is_banned`
 	args := pgx.NamedArgs{
 		"user_id": userID,
 		"chat_id": chatID,
 	}
 
-	if _, err := s.db.Exec(ctx, sql, args); err != nil {
+	tag, err := tx.Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrBanNotFound)
+	}
+
+	if err := logModeration(ctx, tx, "unban", chatID, userID, actorID, "", nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+// ListBans returns bans that haven't expired yet, latest first.
+func (s *Storage) ListBans(ctx context.Context, chatID uint64) ([]chat.Ban, error) {
+	const op = "chat.repository.postgres.ListBans"
+
+	sql := `SELECT chat_id, user_id, COALESCE(ban_reason, ''), COALESCE(banned_by, 0), COALESCE(banned_at, joined_at), banned_until
+		FROM chat_members
+		WHERE chat_id = @chat_id AND is_banned AND (banned_until IS NULL OR banned_until > now())
+		ORDER BY banned_at DESC`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var bans []chat.Ban
+
+	for rows.Next() {
+		var ban chat.Ban
+
+		err := rows.Scan(
+			&ban.ChatID,
+			&ban.UserID,
+			&ban.Reason,
+			&ban.BannedBy,
+			&ban.BannedAt,
+			&ban.ExpiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		bans = append(bans, ban)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bans, nil
+}
+
+func logModeration(ctx context.Context, tx pgx.Tx, action string, chatID, userID, actorID uint64, reason string, expiresAt *time.Time) error {
+	sql := `INSERT INTO chat_moderation_log(chat_id, user_id, actor_user_id, action, reason, expires_at)
+		VALUES(@chat_id, @user_id, @actor_user_id, @action, NULLIF(@reason, ''), @expires_at)`
+	args := pgx.NamedArgs{
+		"chat_id":       chatID,
+		"user_id":       userID,
+		"actor_user_id": actorID,
+		"action":        action,
+		"reason":        reason,
+		"expires_at":    expiresAt,
+	}
+
+	_, err := tx.Exec(ctx, sql, args)
+
+	return err
+}
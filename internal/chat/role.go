@@ -0,0 +1,104 @@
+package chat
+
+import "errors"
+
+var (
+	ErrUnknownRole = errors.New("unknown role")
+)
+
+// Role of a chat member, stored in chat_members.role.
+// Roles are ordered: a member may only act on members of a lower rank.
+type Role string
+
+const (
+	RoleOwner      Role = "owner"
+	RoleAdmin      Role = "admin"
+	RoleModerator  Role = "moderator"
+	RoleMember     Role = "member"
+	RoleSubscriber Role = "subscriber"
+)
+
+var ranks = map[Role]int{
+	RoleOwner:      5,
+	RoleAdmin:      4,
+	RoleModerator:  3,
+	RoleMember:     2,
+	RoleSubscriber: 1,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := ranks[role]; !ok {
+		return "", ErrUnknownRole
+	}
+
+	return role, nil
+}
+
+// DefaultRole is the role of a user joining a chat of the given type:
+// channel members are read-only subscribers until promoted.
+func DefaultRole(chatType string) Role {
+	if chatType == TypeChannel {
+		return RoleSubscriber
+	}
+
+	return RoleMember
+}
+
+func (r Role) Rank() int {
+	return ranks[r]
+}
+
+func (r Role) Outranks(other Role) bool {
+	return r.Rank() > other.Rank()
+}
+
+type Action string
+
+const (
+	ActionPost                 Action = "post"
+	ActionComment              Action = "comment"
+	ActionInvite               Action = "invite"
+	ActionKick                 Action = "kick"
+	ActionBan                  Action = "ban"
+	ActionEditInfo             Action = "edit_info"
+	ActionChangeAddress        Action = "change_address"
+	ActionPin                  Action = "pin"
+	ActionDeleteOthersMessages Action = "delete_others_messages"
+	ActionViewRevisions        Action = "view_revisions"
+	ActionManageRoles          Action = "manage_roles"
+	ActionDeleteChat           Action = "delete_chat"
+)
+
+var permissions = map[Role][]Action{
+	RoleOwner: {
+		ActionPost, ActionComment, ActionInvite, ActionKick, ActionBan, ActionEditInfo, ActionChangeAddress,
+		ActionPin, ActionDeleteOthersMessages, ActionViewRevisions, ActionManageRoles, ActionDeleteChat,
+	},
+	RoleAdmin: {
+		ActionPost, ActionComment, ActionInvite, ActionKick, ActionBan, ActionEditInfo,
+		ActionPin, ActionDeleteOthersMessages, ActionViewRevisions, ActionManageRoles,
+	},
+	RoleModerator: {
+		ActionPost, ActionComment, ActionInvite, ActionKick, ActionBan,
+		ActionPin, ActionDeleteOthersMessages, ActionViewRevisions,
+	},
+	RoleMember: {
+		ActionPost, ActionComment, ActionInvite,
+	},
+	// Subscribers can't post into a channel, only into the comments
+	// threads of its posts.
+	RoleSubscriber: {
+		ActionComment,
+	},
+}
+
+func (r Role) Can(action Action) bool {
+	for _, a := range permissions[r] {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 100
+	SnippetLength    = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Summary is an entry of the caller's chat list.
+type Summary struct {
+	Chat           Chat
+	Title          string
+	Role           Role
+	LastReadMsgID  uint64
+	UnreadCount    uint64
+	LastActivityAt time.Time
+	LastMessage    *MessagePreview
+}
+
+// MessagePreview is the latest message of the chat the user hasn't hidden.
+type MessagePreview struct {
+	ID           uint64
+	AuthorUserID uint64
+	AuthorName   string
+	Snippet      string
+	CreatedAt    time.Time
+	// DeletedAt is set when the message has been deleted for everyone, the
+	// snippet is empty then.
+	DeletedAt *time.Time
+}
+
+// ListCursor is the position after the last returned chat in the list
+// ordered by activity. It is handed out to clients base64-encoded.
+type ListCursor struct {
+	LastActivityAt time.Time
+	ChatID         uint64
+}
+
+func (c ListCursor) String() string {
+	raw := fmt.Sprintf("%d:%d", c.LastActivityAt.UnixMicro(), c.ChatID)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseListCursor(s string) (ListCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ListCursor{}, ErrInvalidCursor
+	}
+
+	var (
+		micro  int64
+		chatID uint64
+	)
+
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &micro, &chatID); err != nil {
+		return ListCursor{}, ErrInvalidCursor
+	}
+
+	return ListCursor{LastActivityAt: time.UnixMicro(micro).UTC(), ChatID: chatID}, nil
+}
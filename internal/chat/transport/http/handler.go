@@ -4,16 +4,21 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"messanger/internal/chat"
 	"messanger/internal/chat/repository"
 	"messanger/internal/chat/usecase"
+	"messanger/internal/lib/auth"
 	"messanger/internal/lib/logger/sl"
 	"net/http"
 )
 
+var (
+	ErrInvalidToken = errors.New("invalid token")
+)
+
 type ChatHandler struct {
-	log             *slog.Logger
-	chatUC          usecase.ChatUC
-	chatUserActions repository.ChatUserActions
+	log    *slog.Logger
+	chatUC usecase.ChatUC
 }
 
 func New(log *slog.Logger, chatUC usecase.ChatUC) ChatHandler {
@@ -30,6 +35,11 @@ func (h *ChatHandler) CreateChannel(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 	)
 
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
 	var createChatDTO CreateChatReqDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&createChatDTO); err != nil {
@@ -46,17 +56,9 @@ func (h *ChatHandler) CreateChannel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chatID, err := h.chatUC.CreateChannel(r.Context(), createChatDTO.Address)
+	chatID, err := h.chatUC.CreateChannel(r.Context(), uid, createChatDTO.Info())
 	if err != nil {
-		// todo: fix. Now it's isn't working
-		if errors.Is(err, repository.ErrChatAlreadyExist) {
-			errDTO := NewErrorDTO(repository.ErrChatAlreadyExist)
-			http.Error(w, errDTO.String(), http.StatusConflict)
-			return
-		}
-
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		h.writeUCError(w, err)
 		return
 	}
 
@@ -72,6 +74,11 @@ func (h *ChatHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 	)
 
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
 	var createChatDTO CreateChatReqDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&createChatDTO); err != nil {
@@ -88,16 +95,9 @@ func (h *ChatHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chatID, err := h.chatUC.CreateGroup(r.Context(), createChatDTO.Address)
+	chatID, err := h.chatUC.CreateGroup(r.Context(), uid, createChatDTO.Info())
 	if err != nil {
-		if errors.Is(err, repository.ErrChatAlreadyExist) {
-			errDTO := NewErrorDTO(repository.ErrChatAlreadyExist)
-			http.Error(w, errDTO.String(), http.StatusConflict)
-			return
-		}
-
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		h.writeUCError(w, err)
 		return
 	}
 
@@ -113,35 +113,37 @@ func (h *ChatHandler) CreatePrivate(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 	)
 
-	var createChatDTO CreateChatReqDTO
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&createChatDTO); err != nil {
+	var createPrivateDTO CreatePrivateReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&createPrivateDTO); err != nil {
 		errDTO := NewErrorDTO(err)
 		log.Warn(errDTO.String(), sl.Err(err))
 		http.Error(w, errDTO.String(), http.StatusInternalServerError)
 		return
 	}
 
-	if err := createChatDTO.Validate(); err != nil {
+	if err := createPrivateDTO.Validate(); err != nil {
 		errDTO := NewErrorDTO(err)
 		log.Error("validation error", sl.Err(err))
 		http.Error(w, errDTO.String(), http.StatusBadRequest)
 		return
 	}
 
-	chatID, err := h.chatUC.CreatePrivate(r.Context(), createChatDTO.Address)
+	chatID, created, err := h.chatUC.CreatePrivate(r.Context(), uid, createPrivateDTO.UserID)
 	if err != nil {
-		if errors.Is(err, repository.ErrChatAlreadyExist) {
-			errDTO := NewErrorDTO(repository.ErrChatAlreadyExist)
-			http.Error(w, errDTO.String(), http.StatusConflict)
-			return
-		}
-
-		errDTO := NewErrorDTO(err)
-		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		h.writeUCError(w, err)
 		return
 	}
 
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+
 	resp := CreateChannelResDTO{ID: chatID}
 
 	json.NewEncoder(w).Encode(resp)
@@ -154,9 +156,12 @@ func (h *ChatHandler) Join(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 	)
 
-	var joinChatDTO JoinChatReqDTO
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
 
-	w.Header().Get("Authorization")
+	var joinChatDTO JoinChatReqDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&joinChatDTO); err != nil {
 		errDTO := NewErrorDTO(err)
@@ -172,13 +177,56 @@ func (h *ChatHandler) Join(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.chatUserActions.Join(r.Context(), joinChatDTO.Role, joinChatDTO.UserID, joinChatDTO.ChatID)
+	err := h.chatUC.Join(r.Context(), uid, uid, joinChatDTO.ChatID, "")
 	if err != nil {
-		errDTO := NewErrorDTO(err)
 		log.Error("joining error", sl.Err(err))
+		h.writeUCError(w, err)
+		return
+	}
+}
+
+func (h *ChatHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	const op = "chat.http.handler.Invite"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var inviteDTO InviteReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&inviteDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := inviteDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
 		http.Error(w, errDTO.String(), http.StatusBadRequest)
 		return
 	}
+
+	if err := h.chatUC.Join(r.Context(), uid, inviteDTO.UserID, chatID, inviteDTO.Role); err != nil {
+		log.Error("invite error", sl.Err(err))
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *ChatHandler) Leave(w http.ResponseWriter, r *http.Request) {
@@ -188,6 +236,11 @@ func (h *ChatHandler) Leave(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 	)
 
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
 	var leaveChatDTO LeaveChatReqDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&leaveChatDTO); err != nil {
@@ -204,11 +257,181 @@ func (h *ChatHandler) Leave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.chatUserActions.Leave(r.Context(), leaveChatDTO.UserID, leaveChatDTO.ChatID)
+	err := h.chatUC.Leave(r.Context(), uid, uid, leaveChatDTO.ChatID)
 	if err != nil {
-		errDTO := NewErrorDTO(err)
 		log.Error("leave error", sl.Err(err))
+		h.writeUCError(w, err)
+		return
+	}
+}
+
+func (h *ChatHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
 		http.Error(w, errDTO.String(), http.StatusBadRequest)
 		return
 	}
+
+	if err := h.chatUC.Delete(r.Context(), uid, chatID); err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChatHandler) Update(w http.ResponseWriter, r *http.Request) {
+	const op = "chat.http.handler.Update"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var updateDTO UpdateChatReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&updateDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := updateDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	cht, err := h.chatUC.UpdateInfo(r.Context(), uid, chatID, updateDTO.InfoUpdate())
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewChatResDTO(cht))
+}
+
+func (h *ChatHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	const op = "chat.http.handler.SetRole"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := ParseUserID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var setRoleDTO SetRoleReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&setRoleDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := setRoleDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.chatUC.SetRole(r.Context(), uid, userID, chatID, chat.Role(setRoleDTO.Role)); err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userID returns the acting user put into the context by the auth middleware.
+func (h *ChatHandler) userID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		errDTO := NewErrorDTO(ErrInvalidToken)
+		http.Error(w, errDTO.String(), http.StatusUnauthorized)
+		return 0, false
+	}
+
+	return principal.UserID, true
+}
+
+func (h *ChatHandler) writeUCError(w http.ResponseWriter, err error) {
+	var status int
+
+	switch {
+	case errors.Is(err, repository.ErrChatAlreadyExist):
+		err, status = repository.ErrChatAlreadyExist, http.StatusConflict
+	case errors.Is(err, repository.ErrChatNotFound):
+		err, status = repository.ErrChatNotFound, http.StatusNotFound
+	case errors.Is(err, repository.ErrMemberNotFound):
+		err, status = repository.ErrMemberNotFound, http.StatusNotFound
+	case errors.Is(err, repository.ErrAlreadyMember):
+		err, status = repository.ErrAlreadyMember, http.StatusConflict
+	case errors.Is(err, repository.ErrPeerNotFound):
+		err, status = repository.ErrPeerNotFound, http.StatusNotFound
+	case errors.Is(err, usecase.ErrSelfPrivateChat):
+		err, status = usecase.ErrSelfPrivateChat, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrPrivateChat):
+		err, status = usecase.ErrPrivateChat, http.StatusForbidden
+	case errors.Is(err, usecase.ErrNotChannel):
+		err, status = usecase.ErrNotChannel, http.StatusBadRequest
+	case errors.Is(err, repository.ErrBanNotFound):
+		err, status = repository.ErrBanNotFound, http.StatusNotFound
+	case errors.Is(err, usecase.ErrBanned):
+		err, status = usecase.ErrBanned, http.StatusForbidden
+	case errors.Is(err, usecase.ErrBanExpired):
+		err, status = usecase.ErrBanExpired, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrOwnerCannotLeave):
+		err, status = usecase.ErrOwnerCannotLeave, http.StatusConflict
+	case errors.Is(err, usecase.ErrNotChatMember):
+		err, status = usecase.ErrNotChatMember, http.StatusForbidden
+	case errors.Is(err, usecase.ErrForbidden):
+		err, status = usecase.ErrForbidden, http.StatusForbidden
+	case errors.Is(err, chat.ErrUnknownRole):
+		err, status = chat.ErrUnknownRole, http.StatusBadRequest
+	case errors.Is(err, chat.ErrInvalidCursor):
+		err, status = chat.ErrInvalidCursor, http.StatusBadRequest
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	errDTO := NewErrorDTO(err)
+	http.Error(w, errDTO.String(), status)
 }
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (h *ChatHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	cursor, limit, err := ParseListQuery(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	summaries, next, hasMore, err := h.chatUC.List(r.Context(), uid, cursor, limit)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	resp := ListChatsResDTO{
+		Chats:   make([]ChatSummaryResDTO, 0, len(summaries)),
+		Cursor:  next,
+		HasMore: hasMore,
+	}
+	for _, sum := range summaries {
+		resp.Chats = append(resp.Chats, NewChatSummaryResDTO(sum))
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
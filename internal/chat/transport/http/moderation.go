@@ -0,0 +1,135 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+)
+
+func (h *ChatHandler) Kick(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, userID, ok := h.memberParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.chatUC.Kick(r.Context(), uid, userID, chatID); err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChatHandler) Ban(w http.ResponseWriter, r *http.Request) {
+	const op = "chat.http.handler.Ban"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, userID, ok := h.memberParams(w, r)
+	if !ok {
+		return
+	}
+
+	var banDTO BanReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&banDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := banDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.chatUC.Ban(r.Context(), uid, userID, chatID, banDTO.Reason, banDTO.ExpiresAt); err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChatHandler) Unban(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, userID, ok := h.memberParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.chatUC.Unban(r.Context(), uid, userID, chatID); err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChatHandler) ListBans(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	bans, err := h.chatUC.ListBans(r.Context(), uid, chatID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	resp := ListBansResDTO{
+		Bans: make([]BanResDTO, 0, len(bans)),
+	}
+	for _, ban := range bans {
+		resp.Bans = append(resp.Bans, NewBanResDTO(ban))
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// memberParams reads {id} and {userID} from the path.
+func (h *ChatHandler) memberParams(w http.ResponseWriter, r *http.Request) (uint64, uint64, bool) {
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	userID, err := ParseUserID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return chatID, userID, true
+}
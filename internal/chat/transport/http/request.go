@@ -3,7 +3,14 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"messanger/internal/chat"
+	"messanger/internal/lib/emoji"
+	"net/http"
+	"strconv"
 	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
 )
 
 var (
@@ -11,33 +18,146 @@ var (
 	ErrChatIdIsEmpty  = errors.New("chat_id is empty")
 	ErrUserIdIsEmpty  = errors.New("user_id is empty")
 	ErrRoleIsEmpty    = errors.New("role is empty")
+	ErrInvalidChatID  = errors.New("invalid chat id")
+	ErrInvalidUserID  = errors.New("invalid user id")
+	ErrReasonTooLong  = errors.New("reason is too long")
+	ErrInvalidLimit   = errors.New("invalid limit")
+	ErrNothingToApply = errors.New("nothing to update")
+	ErrAddressTooLong = errors.New("address is too long")
+	ErrTitleTooLong   = errors.New("title is too long")
+	ErrDescTooLong    = errors.New("description is too long")
+	ErrAvatarTooLong  = errors.New("avatar is too long")
+	ErrTooManyEmoji   = errors.New("too many allowed reactions")
+	ErrInvalidEmoji   = errors.New("allowed reactions must be emoji")
+	ErrDuplicateEmoji = errors.New("allowed reactions repeat an emoji")
+	ErrBanExpired     = errors.New("expires_at is in the past")
+)
+
+const (
+	maxReasonLength      = 512
+	maxAddressLength     = 255
+	maxTitleLength       = 255
+	maxDescriptionLength = 2048
+	maxAvatarLength      = 512
 )
 
+// CreateChatReqDTO sets the chat's info at creation, every field is optional.
+// A chat without an address can only be joined by invite.
 type CreateChatReqDTO struct {
-	Address string `json:"address"`
+	Address     string `json:"address"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Avatar      string `json:"avatar"`
 }
 
 func (c CreateChatReqDTO) Validate() error {
-	if c.Address == "" {
-		return ErrAddressIsEmpty
+	if utf8.RuneCountInString(c.Address) > maxAddressLength {
+		return ErrAddressTooLong
+	}
+	if utf8.RuneCountInString(c.Title) > maxTitleLength {
+		return ErrTitleTooLong
+	}
+	if utf8.RuneCountInString(c.Description) > maxDescriptionLength {
+		return ErrDescTooLong
+	}
+	if utf8.RuneCountInString(c.Avatar) > maxAvatarLength {
+		return ErrAvatarTooLong
 	}
 
 	return nil
 }
 
-type JoinChatReqDTO struct {
-	UserID uint64 `json:"user_id"`
-	ChatID uint64 `json:"chat_id"`
-	Role   string `json:"role"`
+func (c CreateChatReqDTO) Info() chat.Chat {
+	return chat.Chat{
+		Address:     c.Address,
+		Title:       c.Title,
+		Description: c.Description,
+		Avatar:      c.Avatar,
+	}
 }
 
-func (j JoinChatReqDTO) Validate() error {
-	if j.Role == "" {
-		return ErrRoleIsEmpty
+// UpdateChatReqDTO is a partial update, omitted fields are left as they are.
+// An empty address removes the chat's public address, an empty list of
+// allowed reactions lifts the restriction.
+type UpdateChatReqDTO struct {
+	Title            *string   `json:"title"`
+	Description      *string   `json:"description"`
+	Avatar           *string   `json:"avatar"`
+	Address          *string   `json:"address"`
+	AllowedReactions *[]string `json:"allowed_reactions"`
+}
+
+func (d UpdateChatReqDTO) Validate() error {
+	if d.Title == nil && d.Description == nil && d.Avatar == nil && d.Address == nil && d.AllowedReactions == nil {
+		return ErrNothingToApply
+	}
+	if d.Title != nil && utf8.RuneCountInString(*d.Title) > maxTitleLength {
+		return ErrTitleTooLong
+	}
+	if d.Description != nil && utf8.RuneCountInString(*d.Description) > maxDescriptionLength {
+		return ErrDescTooLong
+	}
+	if d.Avatar != nil && utf8.RuneCountInString(*d.Avatar) > maxAvatarLength {
+		return ErrAvatarTooLong
+	}
+	if d.Address != nil && utf8.RuneCountInString(*d.Address) > maxAddressLength {
+		return ErrAddressTooLong
+	}
+	if d.AllowedReactions != nil {
+		return validateAllowedReactions(*d.AllowedReactions)
+	}
+	return nil
+}
+
+func validateAllowedReactions(reactions []string) error {
+	if len(reactions) > chat.MaxAllowedReactions {
+		return ErrTooManyEmoji
+	}
+
+	seen := make(map[string]struct{}, len(reactions))
+	for _, r := range reactions {
+		if !emoji.Valid(r) {
+			return ErrInvalidEmoji
+		}
+		if _, ok := seen[r]; ok {
+			return ErrDuplicateEmoji
+		}
+		seen[r] = struct{}{}
 	}
-	if j.UserID == 0 {
+
+	return nil
+}
+
+func (d UpdateChatReqDTO) InfoUpdate() chat.InfoUpdate {
+	return chat.InfoUpdate{
+		Title:       d.Title,
+		Description: d.Description,
+		Avatar:      d.Avatar,
+		Address:     d.Address,
+
+		AllowedReactions: d.AllowedReactions,
+	}
+}
+
+type CreatePrivateReqDTO struct {
+	UserID uint64 `json:"user_id"`
+}
+
+func (c CreatePrivateReqDTO) Validate() error {
+	if c.UserID == 0 {
 		return ErrUserIdIsEmpty
 	}
+
+	return nil
+}
+
+// JoinChatReqDTO joins the caller to the chat with the chat type's default
+// role.
+type JoinChatReqDTO struct {
+	ChatID uint64 `json:"chat_id"`
+}
+
+func (j JoinChatReqDTO) Validate() error {
 	if j.ChatID == 0 {
 		return ErrChatIdIsEmpty
 	}
@@ -45,20 +165,83 @@ func (j JoinChatReqDTO) Validate() error {
 }
 
 type LeaveChatReqDTO struct {
-	UserID uint64 `json:"user_id"`
 	ChatID uint64 `json:"chat_id"`
 }
 
 func (d *LeaveChatReqDTO) Validate() error {
+	if d.ChatID == 0 {
+		return ErrChatIdIsEmpty
+	}
+	return nil
+}
+
+// InviteReqDTO adds another user to the chat. Role is optional and defaults
+// to the chat type's default role.
+type InviteReqDTO struct {
+	UserID uint64 `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+func (d InviteReqDTO) Validate() error {
 	if d.UserID == 0 {
 		return ErrUserIdIsEmpty
 	}
-	if d.ChatID == 0 {
-		return ErrChatIdIsEmpty
+	if d.Role != "" {
+		if _, err := chat.ParseRole(d.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type SetRoleReqDTO struct {
+	Role string `json:"role"`
+}
+
+func (d SetRoleReqDTO) Validate() error {
+	if d.Role == "" {
+		return ErrRoleIsEmpty
+	}
+	if _, err := chat.ParseRole(d.Role); err != nil {
+		return err
 	}
 	return nil
 }
 
+// BanReqDTO bans a user until ExpiresAt, or forever when it is omitted.
+type BanReqDTO struct {
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (d BanReqDTO) Validate() error {
+	if len(d.Reason) > maxReasonLength {
+		return ErrReasonTooLong
+	}
+	if d.ExpiresAt != nil && !d.ExpiresAt.After(time.Now()) {
+		return ErrBanExpired
+	}
+	return nil
+}
+
+func ParseChatID(r *http.Request) (uint64, error) {
+	chatID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || chatID == 0 {
+		return 0, ErrInvalidChatID
+	}
+
+	return chatID, nil
+}
+
+func ParseUserID(r *http.Request) (uint64, error) {
+	userID, err := strconv.ParseUint(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || userID == 0 {
+		return 0, ErrInvalidUserID
+	}
+
+	return userID, nil
+}
+
 // type GetByAddressReqDTO struct {
 // 	Address string `json:"address"`
 // }
@@ -87,3 +270,20 @@ func (e ErrorDTO) String() string {
 
 	return string(b)
 }
+
+// ParseListQuery reads ?cursor= and ?limit= query parameters.
+func ParseListQuery(r *http.Request) (string, int, error) {
+	q := r.URL.Query()
+
+	var limit int
+
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 {
+			return "", 0, ErrInvalidLimit
+		}
+		limit = l
+	}
+
+	return q.Get("cursor"), limit, nil
+}
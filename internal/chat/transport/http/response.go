@@ -1,5 +1,113 @@
 package http
 
+import (
+	"messanger/internal/chat"
+	"time"
+)
+
 type CreateChannelResDTO struct {
 	ID uint64 `json:"id"`
 }
+
+type ChatResDTO struct {
+	ID          uint64    `json:"id"`
+	Type        string    `json:"type"`
+	Address     string    `json:"address,omitempty"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Avatar      string    `json:"avatar,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	AllowedReactions []string `json:"allowed_reactions,omitempty"`
+}
+
+func NewChatResDTO(cht chat.Chat) ChatResDTO {
+	return ChatResDTO{
+		ID:          cht.ID,
+		Type:        cht.Type,
+		Address:     cht.Address,
+		Title:       cht.Title,
+		Description: cht.Description,
+		Avatar:      cht.Avatar,
+		CreatedAt:   cht.CreatedAt,
+
+		AllowedReactions: cht.AllowedReactions,
+	}
+}
+
+type BanResDTO struct {
+	UserID    uint64     `json:"user_id"`
+	Reason    string     `json:"reason,omitempty"`
+	BannedBy  uint64     `json:"banned_by,omitempty"`
+	BannedAt  time.Time  `json:"banned_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func NewBanResDTO(ban chat.Ban) BanResDTO {
+	return BanResDTO{
+		UserID:    ban.UserID,
+		Reason:    ban.Reason,
+		BannedBy:  ban.BannedBy,
+		BannedAt:  ban.BannedAt,
+		ExpiresAt: ban.ExpiresAt,
+	}
+}
+
+type ListBansResDTO struct {
+	Bans []BanResDTO `json:"bans"`
+}
+
+type MessagePreviewResDTO struct {
+	ID           uint64    `json:"id"`
+	AuthorUserID uint64    `json:"author_user_id"`
+	AuthorName   string    `json:"author_name"`
+	Snippet      string    `json:"snippet"`
+	CreatedAt    time.Time `json:"created_at"`
+	Deleted      bool      `json:"deleted,omitempty"`
+}
+
+type ChatSummaryResDTO struct {
+	ID             uint64                `json:"id"`
+	Type           string                `json:"type"`
+	Address        string                `json:"address,omitempty"`
+	Title          string                `json:"title"`
+	Avatar         string                `json:"avatar,omitempty"`
+	Role           chat.Role             `json:"role"`
+	UnreadCount    uint64                `json:"unread_count"`
+	LastReadMsgID  uint64                `json:"last_read_msg_id"`
+	LastActivityAt time.Time             `json:"last_activity_at"`
+	LastMessage    *MessagePreviewResDTO `json:"last_message"`
+}
+
+func NewChatSummaryResDTO(sum chat.Summary) ChatSummaryResDTO {
+	dto := ChatSummaryResDTO{
+		ID:             sum.Chat.ID,
+		Type:           sum.Chat.Type,
+		Address:        sum.Chat.Address,
+		Title:          sum.Title,
+		Avatar:         sum.Chat.Avatar,
+		Role:           sum.Role,
+		UnreadCount:    sum.UnreadCount,
+		LastReadMsgID:  sum.LastReadMsgID,
+		LastActivityAt: sum.LastActivityAt,
+	}
+
+	if msg := sum.LastMessage; msg != nil {
+		dto.LastMessage = &MessagePreviewResDTO{
+			ID:           msg.ID,
+			AuthorUserID: msg.AuthorUserID,
+			AuthorName:   msg.AuthorName,
+			Snippet:      msg.Snippet,
+			CreatedAt:    msg.CreatedAt,
+			Deleted:      msg.DeletedAt != nil,
+		}
+	}
+
+	return dto
+}
+
+type ListChatsResDTO struct {
+	Chats   []ChatSummaryResDTO `json:"chats"`
+	Cursor  string              `json:"cursor"`
+	HasMore bool                `json:"has_more"`
+}
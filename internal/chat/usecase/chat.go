@@ -2,67 +2,488 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"messanger/internal/chat"
 	"messanger/internal/chat/repository"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"time"
+)
+
+var (
+	ErrNotChatMember    = errors.New("user is not a member of the chat")
+	ErrForbidden        = errors.New("action is not allowed for the role")
+	ErrOwnerCannotLeave = errors.New("owner can't leave the chat, transfer ownership first")
+	ErrPrivateChat      = errors.New("private chats can't be joined")
+	ErrSelfPrivateChat  = errors.New("can't start a private chat with yourself")
+	ErrBanned           = errors.New("user is banned in the chat")
+	ErrNotChannel       = errors.New("reactions can only be restricted in channels")
 )
 
 type ChatUC interface {
-	CreateChannel(ctx context.Context, address string) (uint64, error)
-	CreateGroup(ctx context.Context, address string) (uint64, error)
-	CreatePrivate(ctx context.Context, address string) (uint64, error)
+	// CreateChannel makes the chat with the address, title, description and
+	// avatar of info, the address may be empty.
+	CreateChannel(ctx context.Context, userID uint64, info chat.Chat) (uint64, error)
+	CreateGroup(ctx context.Context, userID uint64, info chat.Chat) (uint64, error)
+	// CreatePrivate returns the private chat between userID and peerID and
+	// reports whether anything has been created.
+	CreatePrivate(ctx context.Context, userID uint64, peerID uint64) (uint64, bool, error)
+	// UpdateInfo applies a partial update of the chat's info. Changing the
+	// address needs more rights than the rest of the info.
+	UpdateInfo(ctx context.Context, actorID uint64, chatID uint64, upd chat.InfoUpdate) (chat.Chat, error)
+	Delete(ctx context.Context, actorID uint64, chatID uint64) error
+	// Join adds userID to the chat. When actorID differs from userID it is an
+	// invite. An empty role means the default role for the chat type.
+	Join(ctx context.Context, actorID uint64, userID uint64, chatID uint64, role string) error
+	// Leave removes userID from the chat. When actorID differs from userID it
+	// is a kick.
+	Leave(ctx context.Context, actorID uint64, userID uint64, chatID uint64) error
+	SetRole(ctx context.Context, actorID uint64, userID uint64, chatID uint64, role chat.Role) error
+
+	Kick(ctx context.Context, actorID uint64, userID uint64, chatID uint64) error
+	Ban(ctx context.Context, actorID uint64, userID uint64, chatID uint64, reason string, expiresAt *time.Time) error
+	Unban(ctx context.Context, actorID uint64, userID uint64, chatID uint64) error
+	ListBans(ctx context.Context, actorID uint64, chatID uint64) ([]chat.Ban, error)
+
+	// List returns a page of the user's chats ordered by last activity, the
+	// cursor of the next page and whether there is one.
+	List(ctx context.Context, userID uint64, cursor string, limit int) ([]chat.Summary, string, bool, error)
 }
 
 type Chat struct {
-	log      *slog.Logger
-	chatRepo repository.ChatRepo
+	log       *slog.Logger
+	chatRepo  repository.ChatRepo
+	publisher event.Publisher
 }
 
-func NewChat(log *slog.Logger, chatRepo repository.ChatRepo) *Chat {
+func NewChat(log *slog.Logger, chatRepo repository.ChatRepo, publisher event.Publisher) *Chat {
 	return &Chat{
-		log:      log,
-		chatRepo: chatRepo,
+		log:       log,
+		chatRepo:  chatRepo,
+		publisher: publisher,
 	}
 }
 
-func (c *Chat) CreateChannel(ctx context.Context, address string) (uint64, error) {
-	newChat := chat.Chat{
-		Type:    "channel",
-		Address: address,
+func (c *Chat) CreateChannel(ctx context.Context, userID uint64, info chat.Chat) (uint64, error) {
+	return c.create(ctx, userID, chat.TypeChannel, info)
+}
+
+func (c *Chat) CreateGroup(ctx context.Context, userID uint64, info chat.Chat) (uint64, error) {
+	return c.create(ctx, userID, chat.TypeGroup, info)
+}
+
+func (c *Chat) CreatePrivate(ctx context.Context, userID uint64, peerID uint64) (uint64, bool, error) {
+	const op = "chat.usecase.chat.CreatePrivate"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("peer_id", peerID),
+	)
+
+	if userID == peerID {
+		return 0, false, fmt.Errorf("%s: %w", op, ErrSelfPrivateChat)
 	}
 
-	chatID, err := c.chatRepo.Create(ctx, newChat)
+	var (
+		chatID uint64
+		joined []uint64
+	)
+
+	err := c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		chatID, joined, err = c.chatRepo.GetOrCreatePrivate(ctx, userID, peerID)
+		if err != nil {
+			return err
+		}
+
+		for _, uid := range joined {
+			if err := c.publish(ctx, event.MemberJoined, chatID, uid, event.MemberPayload{Role: string(chat.RoleMember)}, nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return 0, err
+		log.Error("private chat creation error", sl.Err(err))
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return chatID, nil
+	return chatID, len(joined) > 0, nil
 }
 
-func (c *Chat) CreateGroup(ctx context.Context, address string) (uint64, error) {
+// create makes the chat and its creator's ownership in one transaction.
+func (c *Chat) create(ctx context.Context, userID uint64, typ string, info chat.Chat) (uint64, error) {
+	const op = "chat.usecase.chat.create"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.String("type", typ),
+	)
+
 	newChat := chat.Chat{
-		Type:    "group",
-		Address: address,
+		Type:        typ,
+		Address:     info.Address,
+		Title:       info.Title,
+		Description: info.Description,
+		Avatar:      info.Avatar,
 	}
 
-	chatID, err := c.chatRepo.Create(ctx, newChat)
+	var chatID uint64
+
+	err := c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		chatID, err = c.chatRepo.Create(ctx, newChat, chat.NewMember(chat.RoleOwner, 0, userID))
+		if err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.MemberJoined, chatID, userID, event.MemberPayload{Role: string(chat.RoleOwner)}, nil)
+	})
 	if err != nil {
-		return 0, err
+		log.Error("chat creation error", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return chatID, nil
 }
 
-func (c *Chat) CreatePrivate(ctx context.Context, address string) (uint64, error) {
-	newChat := chat.Chat{
-		Type:    "private",
-		Address: address,
+func (c *Chat) UpdateInfo(ctx context.Context, actorID uint64, chatID uint64, upd chat.InfoUpdate) (chat.Chat, error) {
+	const op = "chat.usecase.chat.UpdateInfo"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	cht, err := c.chatRepo.GetByID(ctx, chatID)
+	if err != nil {
+		return chat.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A private chat is titled after the peer and never has an address.
+	if cht.Type == chat.TypePrivate {
+		return chat.Chat{}, fmt.Errorf("%s: %w", op, ErrPrivateChat)
 	}
 
-	chatID, err := c.chatRepo.Create(ctx, newChat)
+	actor, err := c.authorize(ctx, chatID, actorID, chat.ActionEditInfo)
 	if err != nil {
-		return 0, err
+		log.Warn("access denied", sl.Err(err))
+		return chat.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return chatID, nil
+	if upd.Address != nil && !actor.Role.Can(chat.ActionChangeAddress) {
+		log.Warn("access denied", slog.String("role", string(actor.Role)))
+		return chat.Chat{}, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	if upd.AllowedReactions != nil && cht.Type != chat.TypeChannel {
+		return chat.Chat{}, fmt.Errorf("%s: %w", op, ErrNotChannel)
+	}
+
+	if upd.IsEmpty() {
+		return cht, nil
+	}
+
+	err = c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		cht, err = c.chatRepo.Update(ctx, chatID, upd)
+		if err != nil {
+			return err
+		}
+
+		payload := event.ChatInfoPayload{
+			Title:       cht.Title,
+			Description: cht.Description,
+			Avatar:      cht.Avatar,
+			Address:     cht.Address,
+
+			AllowedReactions: cht.AllowedReactions,
+		}
+
+		return c.publish(ctx, event.ChatUpdated, chatID, actorID, payload, nil)
+	})
+	if err != nil {
+		log.Error("chat update error", sl.Err(err))
+		return chat.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cht, nil
+}
+
+func (c *Chat) Delete(ctx context.Context, actorID uint64, chatID uint64) error {
+	const op = "chat.usecase.chat.Delete"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if _, err := c.authorize(ctx, chatID, actorID, chat.ActionDeleteChat); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Members are gone once the chat is deleted, so the event is addressed
+	// to them explicitly.
+	memberIDs, err := c.chatRepo.ListMemberIDs(ctx, chatID)
+	if err != nil {
+		log.Error("failed to list members", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Delete(ctx, chatID); err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.ChatDeleted, chatID, actorID, nil, memberIDs)
+	})
+	if err != nil {
+		log.Error("chat deletion error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Chat) Join(ctx context.Context, actorID uint64, userID uint64, chatID uint64, role string) error {
+	const op = "chat.usecase.chat.Join"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	cht, err := c.chatRepo.GetByID(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cht.Type == chat.TypePrivate {
+		log.Warn("join to private chat")
+		return fmt.Errorf("%s: %w", op, ErrPrivateChat)
+	}
+
+	existing, err := c.chatRepo.GetMember(ctx, chatID, userID)
+	switch {
+	case err == nil && existing.BanActive(time.Now()):
+		log.Warn("banned user tried to join")
+		return fmt.Errorf("%s: %w", op, ErrBanned)
+	case err == nil && !existing.IsBanned:
+		return fmt.Errorf("%s: %w", op, repository.ErrAlreadyMember)
+	case err != nil && !errors.Is(err, repository.ErrMemberNotFound):
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	newRole := chat.DefaultRole(cht.Type)
+
+	var actor chat.Member
+
+	if actorID != userID {
+		actor, err = c.authorize(ctx, chatID, actorID, chat.ActionInvite)
+		if err != nil {
+			log.Warn("access denied", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if role != "" && chat.Role(role) != newRole {
+		requested, err := chat.ParseRole(role)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		// Picking a non-default role is role management: nobody can grant
+		// a role to themselves or one that isn't below their own.
+		if actorID == userID || !actor.Role.Can(chat.ActionManageRoles) || !actor.Role.Outranks(requested) {
+			log.Warn("access denied", slog.String("role", role))
+			return fmt.Errorf("%s: %w", op, ErrForbidden)
+		}
+
+		newRole = requested
+	}
+
+	payload := event.MemberPayload{Role: string(newRole)}
+	if actorID != userID {
+		payload.ByUserID = actorID
+	}
+
+	err = c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Join(ctx, newRole, userID, chatID); err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.MemberJoined, chatID, userID, payload, nil)
+	})
+	if err != nil {
+		log.Error("joining error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Chat) Leave(ctx context.Context, actorID uint64, userID uint64, chatID uint64) error {
+	const op = "chat.usecase.chat.Leave"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if actorID != userID {
+		return c.Kick(ctx, actorID, userID, chatID)
+	}
+
+	member, err := c.activeMember(ctx, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if member.Role == chat.RoleOwner {
+		return fmt.Errorf("%s: %w", op, ErrOwnerCannotLeave)
+	}
+
+	err = c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Leave(ctx, userID, chatID); err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.MemberLeft, chatID, userID, nil, nil)
+	})
+	if err != nil {
+		log.Error("leave error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Chat) SetRole(ctx context.Context, actorID uint64, userID uint64, chatID uint64, role chat.Role) error {
+	const op = "chat.usecase.chat.SetRole"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.String("role", string(role)),
+	)
+
+	if actorID == userID {
+		return fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	actor, err := c.authorize(ctx, chatID, actorID, chat.ActionManageRoles)
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	target, err := c.activeMember(ctx, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if role == chat.RoleOwner {
+		if actor.Role != chat.RoleOwner {
+			log.Warn("access denied")
+			return fmt.Errorf("%s: %w", op, ErrForbidden)
+		}
+
+		err := c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+			if err := c.chatRepo.TransferOwnership(ctx, chatID, actorID, userID); err != nil {
+				return err
+			}
+
+			if err := c.publish(ctx, event.MemberRole, chatID, actorID, event.MemberPayload{Role: string(chat.RoleAdmin), ByUserID: actorID}, nil); err != nil {
+				return err
+			}
+
+			return c.publish(ctx, event.MemberRole, chatID, userID, event.MemberPayload{Role: string(chat.RoleOwner), ByUserID: actorID}, nil)
+		})
+		if err != nil {
+			log.Error("ownership transfer error", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+
+	if !actor.Role.Outranks(target.Role) || !actor.Role.Outranks(role) {
+		log.Warn("access denied", slog.String("target_role", string(target.Role)))
+		return fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	err = c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.SetRole(ctx, chatID, userID, role); err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.MemberRole, chatID, userID, event.MemberPayload{Role: string(role), ByUserID: actorID}, nil)
+	})
+	if err != nil {
+		log.Error("role update error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// authorize returns the actor's membership if their role permits the action.
+func (c *Chat) authorize(ctx context.Context, chatID, actorID uint64, action chat.Action) (chat.Member, error) {
+	actor, err := c.activeMember(ctx, chatID, actorID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			return chat.Member{}, ErrNotChatMember
+		}
+
+		return chat.Member{}, err
+	}
+
+	if !actor.Role.Can(action) {
+		return chat.Member{}, ErrForbidden
+	}
+
+	return actor, nil
+}
+
+// activeMember returns the membership of a user that isn't banned.
+func (c *Chat) activeMember(ctx context.Context, chatID, userID uint64) (chat.Member, error) {
+	member, err := c.chatRepo.GetMember(ctx, chatID, userID)
+	if err != nil {
+		return chat.Member{}, err
+	}
+
+	if member.IsBanned {
+		return chat.Member{}, repository.ErrMemberNotFound
+	}
+
+	return member, nil
+}
+
+// publish journals the event in the transaction of ctx, so a failure rolls
+// the change back. Subscribers get it once the transaction commits.
+func (c *Chat) publish(ctx context.Context, typ event.Type, chatID, userID uint64, payload any, recipients []uint64) error {
+	ev, err := event.New(typ, chatID, userID, payload)
+	if err != nil {
+		return err
+	}
+	ev.Recipients = recipients
+
+	return c.publisher.Publish(ctx, ev)
 }
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/lib/logger/sl"
+)
+
+func (c *Chat) List(ctx context.Context, userID uint64, cursor string, limit int) ([]chat.Summary, string, bool, error) {
+	const op = "chat.usecase.list.List"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	var after *chat.ListCursor
+
+	if cursor != "" {
+		cur, err := chat.ParseListCursor(cursor)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("%s: %w", op, err)
+		}
+		after = &cur
+	}
+
+	if limit <= 0 {
+		limit = chat.DefaultListLimit
+	}
+	if limit > chat.MaxListLimit {
+		limit = chat.MaxListLimit
+	}
+
+	// One extra row tells whether there is a next page.
+	summaries, err := c.chatRepo.ListUserChats(ctx, userID, after, limit+1)
+	if err != nil {
+		log.Error("failed to list chats", sl.Err(err))
+		return nil, "", false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hasMore := len(summaries) > limit
+	if hasMore {
+		summaries = summaries[:limit]
+	}
+
+	next := cursor
+	if len(summaries) > 0 {
+		last := summaries[len(summaries)-1]
+		next = chat.ListCursor{LastActivityAt: last.LastActivityAt, ChatID: last.Chat.ID}.String()
+	}
+
+	return summaries, next, hasMore, nil
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/chat/repository"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"time"
+)
+
+var (
+	ErrBanExpired = errors.New("ban expiry is in the past")
+)
+
+func (c *Chat) Kick(ctx context.Context, actorID uint64, userID uint64, chatID uint64) error {
+	const op = "chat.usecase.moderation.Kick"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	target, err := c.activeMember(ctx, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := c.authorizeOver(ctx, chatID, actorID, chat.ActionKick, target.Role); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Kick(ctx, chatID, userID, actorID); err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.MemberLeft, chatID, userID, event.MemberPayload{ByUserID: actorID}, nil)
+	})
+	if err != nil {
+		log.Error("kick error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Ban removes the user from the chat and prevents them from joining it again
+// until expiresAt, or forever when it is nil. Users who aren't in the chat
+// can be banned in advance.
+func (c *Chat) Ban(ctx context.Context, actorID uint64, userID uint64, chatID uint64, reason string, expiresAt *time.Time) error {
+	const op = "chat.usecase.moderation.Ban"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return fmt.Errorf("%s: %w", op, ErrBanExpired)
+	}
+
+	if actorID == userID {
+		return fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	targetRole := chat.RoleSubscriber
+	wasMember := false
+
+	target, err := c.chatRepo.GetMember(ctx, chatID, userID)
+	switch {
+	case err == nil && !target.IsBanned:
+		targetRole = target.Role
+		wasMember = true
+	case err != nil && !errors.Is(err, repository.ErrMemberNotFound):
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := c.authorizeOver(ctx, chatID, actorID, chat.ActionBan, targetRole); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Ban(ctx, chat.NewBan(chatID, userID, actorID, reason, expiresAt)); err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.MemberBanned, chatID, userID, event.MemberPayload{
+			ByUserID:  actorID,
+			Reason:    reason,
+			ExpiresAt: expiresAt,
+		}, nil)
+	})
+	if err != nil {
+		log.Error("ban error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user banned", slog.Bool("was_member", wasMember))
+
+	return nil
+}
+
+func (c *Chat) Unban(ctx context.Context, actorID uint64, userID uint64, chatID uint64) error {
+	const op = "chat.usecase.moderation.Unban"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if _, err := c.authorize(ctx, chatID, actorID, chat.ActionBan); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := c.chatRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := c.chatRepo.Unban(ctx, chatID, userID, actorID); err != nil {
+			return err
+		}
+
+		return c.publish(ctx, event.MemberUnbanned, chatID, userID, event.MemberPayload{ByUserID: actorID}, nil)
+	})
+	if err != nil {
+		log.Error("unban error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user unbanned")
+
+	return nil
+}
+
+func (c *Chat) ListBans(ctx context.Context, actorID uint64, chatID uint64) ([]chat.Ban, error) {
+	const op = "chat.usecase.moderation.ListBans"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("actor_id", actorID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if _, err := c.authorize(ctx, chatID, actorID, chat.ActionBan); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	bans, err := c.chatRepo.ListBans(ctx, chatID)
+	if err != nil {
+		log.Error("failed to list bans", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bans, nil
+}
+
+// authorizeOver checks that the actor may perform the action on a member
+// with the target role: moderation only works downwards.
+func (c *Chat) authorizeOver(ctx context.Context, chatID, actorID uint64, action chat.Action, target chat.Role) error {
+	actor, err := c.authorize(ctx, chatID, actorID, action)
+	if err != nil {
+		return err
+	}
+
+	if !actor.Role.Outranks(target) {
+		return ErrForbidden
+	}
+
+	return nil
+}
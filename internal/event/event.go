@@ -0,0 +1,123 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type Type string
+
+const (
+	MessageCreated  Type = "message.created"
+	MessageEdited   Type = "message.edited"
+	MessageDeleted  Type = "message.deleted"
+	MessageHidden   Type = "message.hidden"
+	ReadMarkerMoved Type = "chat.read"
+	ThreadRead      Type = "thread.read"
+	ReactionAdded   Type = "reaction.added"
+	ReactionRemoved Type = "reaction.removed"
+	MessagePinned   Type = "message.pinned"
+	MessageUnpinned Type = "message.unpinned"
+	MemberJoined    Type = "member.joined"
+	MemberLeft      Type = "member.left"
+	MemberRole      Type = "member.role_changed"
+	MemberBanned    Type = "member.banned"
+	MemberUnbanned  Type = "member.unbanned"
+	ChatUpdated     Type = "chat.updated"
+	ChatDeleted     Type = "chat.deleted"
+)
+
+// Event is a domain event scoped to a chat. UserID is the subject of
+// membership events and the author of message events.
+//
+// Events are visible to all chat members unless Recipients is set, in which
+// case only the listed users receive them. Cursor is the event's position in
+// the update log and is empty until the event has been journaled.
+type Event struct {
+	Type       Type            `json:"type"`
+	ChatID     uint64          `json:"chat_id"`
+	UserID     uint64          `json:"user_id,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Time       time.Time       `json:"time"`
+	Cursor     string          `json:"cursor,omitempty"`
+	Recipients []uint64        `json:"recipients,omitempty"`
+}
+
+func New(typ Type, chatID, userID uint64, payload any) (Event, error) {
+	ev := Event{
+		Type:   typ,
+		ChatID: chatID,
+		UserID: userID,
+		Time:   time.Now(),
+	}
+
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return Event{}, err
+		}
+		ev.Payload = b
+	}
+
+	return ev, nil
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, ev Event) error
+}
+
+type MessagePayload struct {
+	ID           uint64     `json:"id"`
+	ChatID       uint64     `json:"chat_id"`
+	AuthorUserID uint64     `json:"author_user_id"`
+	Text         string     `json:"text"`
+	CreatedAt    time.Time  `json:"created_at"`
+	EditedAt     *time.Time `json:"edited_at,omitempty"`
+	ReplyToMsgID uint64     `json:"reply_to_msg_id,omitempty"`
+	ThreadRootID uint64     `json:"thread_root_id,omitempty"`
+	Service      string     `json:"service,omitempty"`
+}
+
+// DeletedPayload lists the messages deleted for everyone or, for
+// message.hidden, for the recipient only.
+type DeletedPayload struct {
+	IDs []uint64 `json:"ids"`
+}
+
+type ReactionPayload struct {
+	MsgID uint64 `json:"msg_id"`
+	Emoji string `json:"emoji"`
+}
+
+type PinPayload struct {
+	MsgID    uint64     `json:"msg_id"`
+	PinnedBy uint64     `json:"pinned_by,omitempty"`
+	PinnedAt *time.Time `json:"pinned_at,omitempty"`
+}
+
+type MemberPayload struct {
+	Role      string     `json:"role,omitempty"`
+	ByUserID  uint64     `json:"by_user_id,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type ReadPayload struct {
+	LastReadMsgID uint64 `json:"last_read_msg_id"`
+}
+
+type ThreadReadPayload struct {
+	RootMsgID     uint64 `json:"root_msg_id"`
+	LastReadMsgID uint64 `json:"last_read_msg_id"`
+}
+
+// ChatInfoPayload carries the chat's info after an update.
+type ChatInfoPayload struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Avatar      string `json:"avatar"`
+	Address     string `json:"address"`
+
+	AllowedReactions []string `json:"allowed_reactions,omitempty"`
+}
@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/auth"
+	"messanger/internal/lib/logger/sl"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 4096
+	sendBufferSize = 256
+
+	// sessionCheckPeriod is how often an open connection checks that its
+	// session hasn't been revoked.
+	sessionCheckPeriod = 30 * time.Second
+)
+
+var (
+	ErrSendBufferFull = errors.New("send buffer is full")
+)
+
+// SessionChecker reports whether the session of a token hasn't been revoked.
+type SessionChecker interface {
+	Active(ctx context.Context, userID uint64, sessionID uint64) (bool, error)
+}
+
+// Client is a single websocket connection of an authenticated user. It lives
+// no longer than the access token it was opened with and its session.
+// chats is guarded by Hub.mu.
+type Client struct {
+	log       *slog.Logger
+	hub       *Hub
+	conn      *websocket.Conn
+	sessions  SessionChecker
+	userID    uint64
+	sessionID uint64
+	expiresAt time.Time
+	chats     map[uint64]struct{}
+
+	send        chan []byte
+	done        chan struct{}
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+}
+
+func NewClient(log *slog.Logger, hub *Hub, conn *websocket.Conn, sessions SessionChecker, principal auth.Principal) *Client {
+	return &Client{
+		log:       log,
+		hub:       hub,
+		conn:      conn,
+		sessions:  sessions,
+		userID:    principal.UserID,
+		sessionID: principal.SessionID,
+		expiresAt: principal.ExpiresAt,
+		chats:     make(map[uint64]struct{}),
+		send:      make(chan []byte, sendBufferSize),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run pumps the connection until it is closed by either side.
+func (c *Client) Run() {
+	go c.writePump()
+	go c.watchSession()
+	c.readPump()
+}
+
+// watchSession closes the connection when its access token expires or its
+// session is revoked. The client reconnects with a fresh token.
+func (c *Client) watchSession() {
+	const op = "gateway.client.watchSession"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", c.userID),
+		slog.Uint64("session_id", c.sessionID),
+	)
+
+	expiry := time.NewTimer(time.Until(c.expiresAt))
+	check := time.NewTicker(sessionCheckPeriod)
+
+	defer func() {
+		expiry.Stop()
+		check.Stop()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-expiry.C:
+			c.close(websocket.ClosePolicyViolation, "token expired")
+			return
+		case <-check.C:
+			ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+			active, err := c.sessions.Active(ctx, c.userID, c.sessionID)
+			cancel()
+
+			if err != nil {
+				// Don't drop everyone while the database is unavailable,
+				// the next check decides.
+				log.Warn("failed to check session", sl.Err(err))
+				continue
+			}
+
+			if !active {
+				log.Info("session revoked, closing connection")
+				c.close(websocket.ClosePolicyViolation, "session revoked")
+				return
+			}
+		}
+	}
+}
+
+// enqueue never blocks. When the buffer is full the client is closed so that
+// a slow reader can't hold back fan-out to everyone else.
+func (c *Client) enqueue(data []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		c.close(websocket.CloseTryAgainLater, "resync required")
+		return false
+	}
+}
+
+func (c *Client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// readPump only handles control frames: clients don't send anything
+// meaningful over the socket yet.
+func (c *Client) readPump() {
+	const op = "gateway.client.readPump"
+
+	defer func() {
+		c.hub.Unregister(c)
+		c.close(websocket.CloseNormalClosure, "")
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				c.log.Warn("unexpected close", slog.String("op", op), sl.Err(err))
+			}
+			return
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, c.closeReason),
+			)
+			return
+		}
+	}
+}
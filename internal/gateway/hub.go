@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"sync"
+)
+
+type MembershipReader interface {
+	ListUserChatIDs(ctx context.Context, userID uint64) ([]uint64, error)
+}
+
+// Hub keeps track of connected clients and fans events out to the
+// clients subscribed to the event's chat. Delivery never blocks: a client
+// whose send buffer is full is disconnected and has to resync.
+type Hub struct {
+	log     *slog.Logger
+	members MembershipReader
+
+	mu    sync.RWMutex
+	chats map[uint64]map[*Client]struct{}
+	users map[uint64]map[*Client]struct{}
+	// pending holds the membership changes that reach clients while their
+	// chats are being loaded, true for a join.
+	pending map[*Client]map[uint64]bool
+}
+
+func NewHub(log *slog.Logger, members MembershipReader) *Hub {
+	return &Hub{
+		log:     log,
+		members: members,
+		chats:   make(map[uint64]map[*Client]struct{}),
+		users:   make(map[uint64]map[*Client]struct{}),
+		pending: make(map[*Client]map[uint64]bool),
+	}
+}
+
+// Register subscribes the client to every chat its user belongs to. The
+// client is added before its chats are loaded, so joins and leaves that
+// happen meanwhile aren't missed. They win over the loaded chat set, which
+// may have been read before them.
+func (h *Hub) Register(ctx context.Context, c *Client) error {
+	const op = "gateway.hub.Register"
+
+	h.mu.Lock()
+	if h.users[c.userID] == nil {
+		h.users[c.userID] = make(map[*Client]struct{})
+	}
+	h.users[c.userID][c] = struct{}{}
+	h.pending[c] = make(map[uint64]bool)
+	h.mu.Unlock()
+
+	chatIDs, err := h.members.ListUserChatIDs(ctx, c.userID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	changed := h.pending[c]
+	delete(h.pending, c)
+
+	if err != nil {
+		h.remove(c)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, chatID := range chatIDs {
+		if _, ok := changed[chatID]; !ok {
+			h.subscribe(c, chatID)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(c)
+}
+
+// Handle delivers an event from the event bus to subscribed clients.
+func (h *Hub) Handle(ctx context.Context, ev event.Event) {
+	const op = "gateway.hub.Handle"
+
+	recipients := ev.Recipients
+	ev.Recipients = nil
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		h.log.Error("failed to encode event", slog.String("op", op), sl.Err(err))
+		return
+	}
+
+	deliver := func() {
+		if len(recipients) > 0 {
+			h.direct(recipients, data)
+			return
+		}
+		h.broadcast(ev.ChatID, data)
+	}
+
+	switch ev.Type {
+	case event.MemberJoined:
+		h.mu.Lock()
+		for c := range h.users[ev.UserID] {
+			h.subscribe(c, ev.ChatID)
+			h.track(c, ev.ChatID, true)
+		}
+		h.mu.Unlock()
+
+		deliver()
+	case event.MemberLeft, event.MemberBanned:
+		deliver()
+
+		h.mu.Lock()
+		for c := range h.users[ev.UserID] {
+			h.unsubscribe(c, ev.ChatID)
+			h.track(c, ev.ChatID, false)
+		}
+		h.mu.Unlock()
+	case event.ChatDeleted:
+		deliver()
+
+		h.mu.Lock()
+		for c := range h.chats[ev.ChatID] {
+			h.unsubscribe(c, ev.ChatID)
+		}
+		for c := range h.pending {
+			h.track(c, ev.ChatID, false)
+		}
+		h.mu.Unlock()
+	default:
+		deliver()
+	}
+}
+
+func (h *Hub) broadcast(chatID uint64, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.chats[chatID] {
+		h.send(c, data)
+	}
+}
+
+func (h *Hub) direct(userIDs []uint64, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, userID := range userIDs {
+		for c := range h.users[userID] {
+			h.send(c, data)
+		}
+	}
+}
+
+func (h *Hub) send(c *Client, data []byte) {
+	if !c.enqueue(data) {
+		h.log.Warn("slow client dropped",
+			slog.String("op", "gateway.hub.send"),
+			slog.Uint64("user_id", c.userID),
+			sl.Err(ErrSendBufferFull),
+		)
+	}
+}
+
+// remove, track, subscribe and unsubscribe must be called with h.mu held.
+func (h *Hub) remove(c *Client) {
+	for chatID := range c.chats {
+		h.unsubscribe(c, chatID)
+	}
+
+	if conns, ok := h.users[c.userID]; ok {
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(h.users, c.userID)
+		}
+	}
+}
+
+// track records a membership change for a client whose chats are still
+// being loaded.
+func (h *Hub) track(c *Client, chatID uint64, joined bool) {
+	if changed, ok := h.pending[c]; ok {
+		changed[chatID] = joined
+	}
+}
+
+func (h *Hub) subscribe(c *Client, chatID uint64) {
+	if h.chats[chatID] == nil {
+		h.chats[chatID] = make(map[*Client]struct{})
+	}
+	h.chats[chatID][c] = struct{}{}
+	c.chats[chatID] = struct{}{}
+}
+
+func (h *Hub) unsubscribe(c *Client, chatID uint64) {
+	delete(c.chats, chatID)
+
+	if conns, ok := h.chats[chatID]; ok {
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(h.chats, chatID)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"messanger/internal/event"
+	"messanger/internal/lib/auth"
+	"slices"
+	"testing"
+)
+
+// fakeMembers returns chatIDs and lets the hub handle during while the chats
+// are being loaded.
+type fakeMembers struct {
+	hub     *Hub
+	chatIDs []uint64
+	err     error
+	during  []event.Event
+}
+
+func (f *fakeMembers) ListUserChatIDs(ctx context.Context, _ uint64) ([]uint64, error) {
+	for _, ev := range f.during {
+		f.hub.Handle(ctx, ev)
+	}
+
+	return f.chatIDs, f.err
+}
+
+func TestHubRegister(t *testing.T) {
+	const userID = 1
+
+	errLoad := errors.New("load failed")
+
+	tests := []struct {
+		name    string
+		loaded  []uint64
+		during  []event.Event
+		err     error
+		want    []uint64
+		wantErr error
+	}{
+		{
+			name:   "loaded chats",
+			loaded: []uint64{1, 2},
+			want:   []uint64{1, 2},
+		},
+		{
+			name:   "joined while loading",
+			loaded: []uint64{1},
+			during: []event.Event{{Type: event.MemberJoined, ChatID: 2, UserID: userID}},
+			want:   []uint64{1, 2},
+		},
+		{
+			name:   "left after the chats were read",
+			loaded: []uint64{1, 2},
+			during: []event.Event{{Type: event.MemberLeft, ChatID: 2, UserID: userID}},
+			want:   []uint64{1},
+		},
+		{
+			name:   "banned after the chats were read",
+			loaded: []uint64{1, 2},
+			during: []event.Event{{Type: event.MemberBanned, ChatID: 1, UserID: userID}},
+			want:   []uint64{2},
+		},
+		{
+			name:   "chat deleted after the chats were read",
+			loaded: []uint64{1, 2},
+			during: []event.Event{{Type: event.ChatDeleted, ChatID: 1}},
+			want:   []uint64{2},
+		},
+		{
+			name:   "joined and left while loading",
+			loaded: []uint64{1},
+			during: []event.Event{
+				{Type: event.MemberJoined, ChatID: 2, UserID: userID},
+				{Type: event.MemberLeft, ChatID: 2, UserID: userID},
+			},
+			want: []uint64{1},
+		},
+		{
+			name:   "another user joined",
+			loaded: []uint64{1},
+			during: []event.Event{{Type: event.MemberJoined, ChatID: 2, UserID: userID + 1}},
+			want:   []uint64{1},
+		},
+		{
+			name:    "load error",
+			during:  []event.Event{{Type: event.MemberJoined, ChatID: 2, UserID: userID}},
+			err:     errLoad,
+			wantErr: errLoad,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slog.New(slog.DiscardHandler)
+
+			members := &fakeMembers{chatIDs: tt.loaded, err: tt.err, during: tt.during}
+			hub := NewHub(log, members)
+			members.hub = hub
+
+			c := NewClient(log, hub, nil, nil, auth.Principal{UserID: userID})
+
+			err := hub.Register(context.Background(), c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Register: err = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := slices.Sorted(maps.Keys(c.chats)); !slices.Equal(got, tt.want) {
+				t.Errorf("client chats = %v, want %v", got, tt.want)
+			}
+
+			var subscribed []uint64
+			for chatID, conns := range hub.chats {
+				if _, ok := conns[c]; ok {
+					subscribed = append(subscribed, chatID)
+				}
+			}
+			if slices.Sort(subscribed); !slices.Equal(subscribed, tt.want) {
+				t.Errorf("hub chats = %v, want %v", subscribed, tt.want)
+			}
+
+			if _, ok := hub.users[userID][c]; ok != (tt.wantErr == nil) {
+				t.Errorf("client registered = %v after err = %v", ok, err)
+			}
+
+			if len(hub.pending) != 0 {
+				t.Errorf("%d clients left pending", len(hub.pending))
+			}
+		})
+	}
+}
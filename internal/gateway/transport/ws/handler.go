@@ -0,0 +1,156 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/gateway"
+	"messanger/internal/lib/jwt"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+type GatewayHandler struct {
+	log      *slog.Logger
+	hub      *gateway.Hub
+	keyring  *jwt.Keyring
+	sessions gateway.SessionChecker
+	upgrader websocket.Upgrader
+}
+
+// New makes the websocket handler. Browser pages may connect from their own
+// host or from allowedOrigins, such as "https://app.example.com".
+func New(log *slog.Logger, hub *gateway.Hub, keyring *jwt.Keyring, sessions gateway.SessionChecker, allowedOrigins []string) *GatewayHandler {
+	return &GatewayHandler{
+		log:      log,
+		hub:      hub,
+		keyring:  keyring,
+		sessions: sessions,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin(allowedOrigins),
+		},
+	}
+}
+
+// checkOrigin lets in clients that send no Origin, which aren't browsers,
+// pages of the same host and the allowed origins.
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+
+		if strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+
+		for _, o := range allowed {
+			if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Connect upgrades the request to a websocket. Browsers can't set headers on
+// websocket requests, so the token may also be passed as ?token=.
+func (h *GatewayHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	const op = "gateway.ws.handler.Connect"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	tokenString, err := jwt.FromRequest(r)
+	if err != nil {
+		tokenString = r.URL.Query().Get("token")
+	}
+
+	claims, err := jwt.Parse(tokenString, h.keyring)
+	if err != nil {
+		errDTO := NewErrorDTO(ErrInvalidToken)
+		http.Error(w, errDTO.String(), http.StatusUnauthorized)
+		return
+	}
+
+	principal, err := claims.Principal()
+	if err != nil {
+		errDTO := NewErrorDTO(ErrInvalidToken)
+		http.Error(w, errDTO.String(), http.StatusUnauthorized)
+		return
+	}
+	uid := principal.UserID
+
+	active, err := h.sessions.Active(r.Context(), uid, principal.SessionID)
+	if err != nil {
+		log.Error("failed to check session", sl.Err(err))
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		return
+	}
+	if !active {
+		errDTO := NewErrorDTO(ErrInvalidToken)
+		http.Error(w, errDTO.String(), http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		log.Warn("upgrade error", sl.Err(err))
+		return
+	}
+
+	client := gateway.NewClient(h.log, h.hub, conn, h.sessions, principal)
+
+	if err := h.hub.Register(r.Context(), client); err != nil {
+		log.Error("failed to register client", sl.Err(err))
+		conn.WriteMessage(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "subscription failed"),
+		)
+		conn.Close()
+		return
+	}
+
+	log.Debug("client connected", slog.Uint64("user_id", uid))
+
+	client.Run()
+}
+
+type ErrorDTO struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+func NewErrorDTO(err error) ErrorDTO {
+	return ErrorDTO{
+		Message: err.Error(),
+		Time:    time.Now(),
+	}
+}
+
+func (e ErrorDTO) String() string {
+	b, _ := json.MarshalIndent(e, "", "    ")
+
+	return string(b)
+}
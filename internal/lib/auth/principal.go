@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Principal is the authenticated user of a request.
+type Principal struct {
+	UserID    uint64
+	SessionID uint64
+	Name      string
+	Login     string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal put into the context by the auth
+// middleware.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
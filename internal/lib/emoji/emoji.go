@@ -0,0 +1,43 @@
+// Package emoji tells emoji apart from arbitrary text, which is enough to
+// keep reactions short and symbolic without a full emoji table.
+package emoji
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength is the longest emoji in code points. ZWJ sequences such as
+// families take up to 11 of them.
+const MaxLength = 16
+
+const (
+	zeroWidthJoiner = '\u200d'
+	keycap          = '\u20e3'
+)
+
+// Valid reports whether s looks like a single emoji: symbols with the
+// modifiers, joiners and selectors emoji sequences are made of, and no
+// letters, spaces or control characters.
+func Valid(s string) bool {
+	if s == "" || !utf8.ValidString(s) || utf8.RuneCountInString(s) > MaxLength {
+		return false
+	}
+
+	var symbol bool
+
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r), r == keycap:
+			symbol = true
+		case unicode.Is(unicode.Sk, r), unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r):
+		case r == zeroWidthJoiner, unicode.Is(unicode.Variation_Selector, r):
+		case r >= 0xe0020 && r <= 0xe007f: // tags of subdivision flags
+		case r == '#', r == '*', r >= '0' && r <= '9': // keycap bases
+		default:
+			return false
+		}
+	}
+
+	return symbol
+}
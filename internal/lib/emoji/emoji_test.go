@@ -0,0 +1,40 @@
+package emoji
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{"thumbs up", "\U0001F44D", true},
+		{"heart with variation selector", "❤️", true},
+		{"skin tone", "\U0001F44D\U0001F3FD", true},
+		{"family", "\U0001F468‍\U0001F469‍\U0001F467‍\U0001F466", true},
+		{"flag", "\U0001F1FA\U0001F1E6", true},
+		{"subdivision flag", "\U0001F3F4\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", true},
+		{"keycap", "1️⃣", true},
+		{"empty", "", false},
+		{"letter", "a", false},
+		{"digit", "1", false},
+		{"space", " ", false},
+		{"text with emoji", "ok\U0001F44D", false},
+		{"emoji with space", "\U0001F44D ", false},
+		{"control character", "\U0001F44D\n", false},
+		{"modifiers only", "️‍", false},
+		{"invalid utf-8", "\xff", false},
+		{"too long", strings.Repeat("\U0001F44D", MaxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Valid(tt.s); got != tt.want {
+				t.Errorf("Valid(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package eventbus
+
+import (
+	"context"
+	"messanger/internal/event"
+	"sync"
+)
+
+type Handler func(ctx context.Context, ev event.Event)
+
+// Bus delivers published events to every subscriber. Depending on the
+// backend subscribers may live in other instances of the app.
+type Bus interface {
+	event.Publisher
+	Subscribe(handler Handler) (unsubscribe func())
+}
+
+// subscribers is the local handler registry shared by the backends.
+type subscribers struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers map[uint64]Handler
+}
+
+func newSubscribers() *subscribers {
+	return &subscribers{
+		handlers: make(map[uint64]Handler),
+	}
+}
+
+func (s *subscribers) add(handler Handler) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.handlers[id] = handler
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.handlers, id)
+	}
+}
+
+func (s *subscribers) dispatch(ctx context.Context, ev event.Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, handler := range s.handlers {
+		handler(ctx, ev)
+	}
+}
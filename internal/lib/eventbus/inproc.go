@@ -0,0 +1,28 @@
+package eventbus
+
+import (
+	"context"
+	"messanger/internal/event"
+)
+
+// InProc delivers events synchronously to subscribers of the same process.
+// It is meant for tests and single-instance deployments.
+type InProc struct {
+	subs *subscribers
+}
+
+func NewInProc() *InProc {
+	return &InProc{
+		subs: newSubscribers(),
+	}
+}
+
+func (b *InProc) Publish(ctx context.Context, ev event.Event) error {
+	b.subs.dispatch(ctx, ev)
+
+	return nil
+}
+
+func (b *InProc) Subscribe(handler Handler) func() {
+	return b.subs.add(handler)
+}
@@ -0,0 +1,82 @@
+package eventbus
+
+import (
+	"context"
+	"messanger/internal/event"
+	"testing"
+)
+
+func TestInProcFanOut(t *testing.T) {
+	tests := []struct {
+		name        string
+		subscribers int
+		unsubscribe []int
+		want        []int
+	}{
+		{"no subscribers", 0, nil, nil},
+		{"one subscriber", 1, nil, []int{1}},
+		{"every subscriber", 3, nil, []int{1, 1, 1}},
+		{"unsubscribed one", 3, []int{1}, []int{1, 0, 1}},
+		{"unsubscribed twice", 2, []int{0, 0}, []int{0, 1}},
+		{"unsubscribed all", 2, []int{0, 1}, []int{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewInProc()
+
+			got := make([]int, tt.subscribers)
+			unsubs := make([]func(), tt.subscribers)
+
+			for i := range tt.subscribers {
+				unsubs[i] = bus.Subscribe(func(ctx context.Context, ev event.Event) {
+					if ev.Type != event.MessageCreated || ev.ChatID != 7 {
+						t.Errorf("subscriber %d got %s in chat %d", i, ev.Type, ev.ChatID)
+					}
+					got[i]++
+				})
+			}
+
+			for _, i := range tt.unsubscribe {
+				unsubs[i]()
+			}
+
+			if err := bus.Publish(context.Background(), event.Event{Type: event.MessageCreated, ChatID: 7}); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("subscriber %d got %d events, want %d", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInProcOrder(t *testing.T) {
+	bus := NewInProc()
+
+	var got []event.Type
+
+	bus.Subscribe(func(ctx context.Context, ev event.Event) {
+		got = append(got, ev.Type)
+	})
+
+	want := []event.Type{event.MessageCreated, event.MessageEdited, event.MessageDeleted}
+
+	for _, typ := range want {
+		if err := bus.Publish(context.Background(), event.Event{Type: typ}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d is %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	channel = "messanger_events"
+
+	// Postgres rejects NOTIFY payloads of 8000 bytes and more.
+	maxNotifyPayload = 7900
+
+	spillRetention   = 5 * time.Minute
+	cleanupPeriod    = time.Minute
+	reconnectBackoff = time.Second
+	maxBackoff       = 30 * time.Second
+)
+
+// envelope is the NOTIFY payload. Events that don't fit are stored in
+// bus_events and only their Ref is sent.
+type envelope struct {
+	Event *event.Event `json:"e,omitempty"`
+	Ref   uint64       `json:"ref,omitempty"`
+}
+
+// Postgres fans events out across app instances with LISTEN/NOTIFY.
+// Local subscribers receive events through the same notification path as
+// remote ones, so every instance sees events in the same order.
+//
+// Events are published through a pool, which replaces broken connections,
+// and received on a dedicated connection that Run re-dials.
+type Postgres struct {
+	log   *slog.Logger
+	dbURL string
+	subs  *subscribers
+	db    *pgxpool.Pool
+}
+
+func NewPostgres(ctx context.Context, log *slog.Logger, dbURL string) (*Postgres, error) {
+	const op = "eventbus.postgres.NewPostgres"
+
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Postgres{
+		log:   log,
+		dbURL: dbURL,
+		subs:  newSubscribers(),
+		db:    pool,
+	}, nil
+}
+
+func (b *Postgres) Close(ctx context.Context) error {
+	b.db.Close()
+
+	return nil
+}
+
+func (b *Postgres) Subscribe(handler Handler) func() {
+	return b.subs.add(handler)
+}
+
+func (b *Postgres) Publish(ctx context.Context, ev event.Event) error {
+	const op = "eventbus.postgres.Publish"
+
+	payload, err := encode(ctx, ev, b.spill)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := b.db.Exec(ctx, `SELECT pg_notify(@channel, @payload)`, pgx.NamedArgs{
+		"channel": channel,
+		"payload": string(payload),
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// encode makes the NOTIFY payload of the event. An event that doesn't fit
+// is handed to spill and only the ID it returns is sent.
+func encode(ctx context.Context, ev event.Event, spill func(ctx context.Context, ev event.Event) (uint64, error)) ([]byte, error) {
+	payload, err := json.Marshal(envelope{Event: &ev})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) <= maxNotifyPayload {
+		return payload, nil
+	}
+
+	id, err := spill(ctx, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope{Ref: id})
+}
+
+// decode reads a NOTIFY payload, looking spilled events up with fetch.
+func decode(ctx context.Context, payload string, fetch func(ctx context.Context, id uint64) (event.Event, error)) (event.Event, error) {
+	var env envelope
+
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		return event.Event{}, err
+	}
+
+	if env.Event != nil {
+		return *env.Event, nil
+	}
+
+	if env.Ref == 0 {
+		return event.Event{}, errors.New("notification has neither an event nor a ref")
+	}
+
+	return fetch(ctx, env.Ref)
+}
+
+// spill stores an oversized event for listeners to fetch.
+func (b *Postgres) spill(ctx context.Context, ev event.Event) (uint64, error) {
+	sql := `INSERT INTO bus_events(payload) VALUES(@payload) RETURNING id`
+	args := pgx.NamedArgs{
+		"payload": ev,
+	}
+
+	var id uint64
+
+	if err := b.db.QueryRow(ctx, sql, args).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Run listens for notifications until ctx is cancelled, reconnecting with
+// backoff when the listening connection breaks.
+func (b *Postgres) Run(ctx context.Context) {
+	const op = "eventbus.postgres.Run"
+
+	log := b.log.With(
+		slog.String("op", op),
+	)
+
+	backoff := reconnectBackoff
+
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Error("listener stopped, reconnecting", sl.Err(err), slog.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (b *Postgres) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.dbURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+
+	lastCleanup := time.Now()
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, cleanupPeriod)
+		n, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+
+		if n != nil {
+			b.handle(ctx, conn, n.Payload)
+		}
+
+		if time.Since(lastCleanup) >= cleanupPeriod {
+			b.cleanup(ctx, conn)
+			lastCleanup = time.Now()
+		}
+	}
+}
+
+func (b *Postgres) handle(ctx context.Context, conn *pgx.Conn, payload string) {
+	const op = "eventbus.postgres.handle"
+
+	ev, err := decode(ctx, payload, func(ctx context.Context, id uint64) (event.Event, error) {
+		return fetch(ctx, conn, id)
+	})
+	if err != nil {
+		b.log.Error("failed to read notification", slog.String("op", op), sl.Err(err))
+		return
+	}
+
+	b.subs.dispatch(ctx, ev)
+}
+
+func fetch(ctx context.Context, conn *pgx.Conn, id uint64) (event.Event, error) {
+	sql := `SELECT payload FROM bus_events WHERE id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	var ev event.Event
+
+	if err := conn.QueryRow(ctx, sql, args).Scan(&ev); err != nil {
+		return event.Event{}, fmt.Errorf("spilled event %d: %w", id, err)
+	}
+
+	return ev, nil
+}
+
+// cleanup removes spilled events that every listener has had time to fetch.
+func (b *Postgres) cleanup(ctx context.Context, conn *pgx.Conn) {
+	const op = "eventbus.postgres.cleanup"
+
+	sql := `DELETE FROM bus_events WHERE created_at < now() - make_interval(secs => @retention)`
+	args := pgx.NamedArgs{
+		"retention": spillRetention.Seconds(),
+	}
+
+	if _, err := conn.Exec(ctx, sql, args); err != nil {
+		b.log.Warn("failed to clean up spilled events", slog.String("op", op), sl.Err(err))
+	}
+}
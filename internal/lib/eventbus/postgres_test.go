@@ -0,0 +1,149 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"messanger/internal/event"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// spillStore stands in for bus_events.
+type spillStore struct {
+	events  map[uint64]event.Event
+	spilled int
+	fetched int
+}
+
+func newSpillStore() *spillStore {
+	return &spillStore{
+		events: make(map[uint64]event.Event),
+	}
+}
+
+func (s *spillStore) spill(ctx context.Context, ev event.Event) (uint64, error) {
+	s.spilled++
+
+	// Stored as jsonb, so the event comes back through JSON.
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return 0, err
+	}
+
+	var stored event.Event
+
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return 0, err
+	}
+
+	id := uint64(len(s.events) + 1)
+	s.events[id] = stored
+
+	return id, nil
+}
+
+func (s *spillStore) fetch(ctx context.Context, id uint64) (event.Event, error) {
+	s.fetched++
+
+	ev, ok := s.events[id]
+	if !ok {
+		return event.Event{}, errors.New("not found")
+	}
+
+	return ev, nil
+}
+
+func testEvent(text string) event.Event {
+	payload, _ := json.Marshal(event.MessagePayload{ID: 1, ChatID: 7, Text: text})
+
+	return event.Event{
+		Type:       event.MessageCreated,
+		ChatID:     7,
+		UserID:     3,
+		Payload:    payload,
+		Time:       time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Cursor:     "cursor",
+		Recipients: []uint64{3, 4},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		ev        event.Event
+		wantSpill bool
+	}{
+		{"small event", testEvent("hello"), false},
+		{"empty text", testEvent(""), false},
+		{"large event", testEvent(strings.Repeat("a", maxNotifyPayload)), true},
+		{"large multibyte text", testEvent(strings.Repeat("я", maxNotifyPayload/2)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newSpillStore()
+
+			payload, err := encode(ctx, tt.ev, store.spill)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			if len(payload) > maxNotifyPayload {
+				t.Errorf("payload is %d bytes, want at most %d", len(payload), maxNotifyPayload)
+			}
+
+			if spilled := store.spilled > 0; spilled != tt.wantSpill {
+				t.Errorf("spilled = %v, want %v", spilled, tt.wantSpill)
+			}
+
+			got, err := decode(ctx, string(payload), store.fetch)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			if store.fetched != store.spilled {
+				t.Errorf("fetched %d times, spilled %d", store.fetched, store.spilled)
+			}
+
+			if !reflect.DeepEqual(got, tt.ev) {
+				t.Errorf("decode = %+v, want %+v", got, tt.ev)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"malformed", "{"},
+		{"empty envelope", "{}"},
+		{"unknown ref", `{"ref":42}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newSpillStore()
+
+			if _, err := decode(context.Background(), tt.payload, store.fetch); err == nil {
+				t.Errorf("decode(%q) succeeded, want an error", tt.payload)
+			}
+		})
+	}
+}
+
+func TestEncodeSpillError(t *testing.T) {
+	spillErr := errors.New("insert failed")
+
+	_, err := encode(context.Background(), testEvent(strings.Repeat("a", maxNotifyPayload)), func(ctx context.Context, ev event.Event) (uint64, error) {
+		return 0, spillErr
+	})
+	if !errors.Is(err, spillErr) {
+		t.Errorf("encode error = %v, want %v", err, spillErr)
+	}
+}
@@ -1,71 +1,138 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"messanger/internal/lib/auth"
 	"messanger/internal/user"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func NewToken(user user.User, secret string, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+const (
+	Issuer   = "messanger"
+	Audience = "messanger"
+)
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["uid"] = user.ID
-	claims["name"] = user.Name
-	claims["login"] = user.Login
-	claims["exp"] = time.Now().Add(duration).Unix()
+var (
+	ErrInvalidSubject = errors.New("invalid subject in token claims")
+	ErrNoToken        = errors.New("token is empty")
+	ErrNoSession      = errors.New("no session in token claims")
+)
 
-	tokenString, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return "", err
+// Claims are the claims of an access token. The user's ID is the subject,
+// SessionID is the login the token has been issued for.
+type Claims struct {
+	Name      string `json:"name"`
+	Login     string `json:"login"`
+	SessionID uint64 `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// UserID parses the subject claim.
+func (c *Claims) UserID() (uint64, error) {
+	uid, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil || uid == 0 {
+		return 0, ErrInvalidSubject
 	}
 
-	return tokenString, nil
+	return uid, nil
 }
 
-func ParseTokenWithClaims(token *jwt.Token) (jwt.MapClaims, error) {
-	claims := jwt.MapClaims{}
+func (c *Claims) Principal() (auth.Principal, error) {
+	uid, err := c.UserID()
+	if err != nil {
+		return auth.Principal{}, err
+	}
 
-	_, err := jwt.ParseWithClaims(token.Raw, claims, func(t *jwt.Token) (any, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	if c.SessionID == 0 {
+		return auth.Principal{}, ErrNoSession
+	}
 
-	if err != nil {
-		return jwt.MapClaims{}, err
+	p := auth.Principal{
+		UserID:    uid,
+		SessionID: c.SessionID,
+		Name:      c.Name,
+		Login:     c.Login,
+		TokenID:   c.ID,
+	}
+	if c.ExpiresAt != nil {
+		p.ExpiresAt = c.ExpiresAt.Time
 	}
 
-	return claims, nil
+	return p, nil
 }
 
-func ValidateToken(w http.ResponseWriter, r *http.Request) *jwt.Token {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return nil
+func NewToken(user user.User, sessionID uint64, keyring *Keyring, duration time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := Claims{
+		Name:      user.Name,
+		Login:     user.Login,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   strconv.FormatUint(user.ID, 10),
+			Audience:  jwt.ClaimStrings{Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
 	}
 
-	tokenString := strings.Split(authHeader, " ")[1]
-	if tokenString == "" {
-		return nil
+	tokenString, err := keyring.Sign(claims)
+	if err != nil {
+		return "", err
 	}
 
-	return VerifyToken(tokenString)
+	return tokenString, nil
 }
 
-func VerifyToken(tokenString string) *jwt.Token {
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+// Parse verifies the token's signature with the keyring and checks its
+// registered claims.
+func Parse(tokenString string, keyring *Keyring) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, keyring.Keyfunc,
+		jwt.WithValidMethods(keyring.Methods()),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// FromRequest returns the bearer token of the Authorization header.
+func FromRequest(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrNoToken
 	}
 
-	if !token.Valid {
-		return nil
+	return token, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
 
-	return token
+	return hex.EncodeToString(b), nil
 }
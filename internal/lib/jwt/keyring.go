@@ -0,0 +1,280 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrNoSigningKey   = errors.New("signing key has no private part")
+	ErrDuplicateKey   = errors.New("duplicate key id")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+)
+
+// Key is a named key of a Keyring. Keys without a private part can only
+// verify tokens.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	private any
+	public  any
+}
+
+func NewHMACKey(kid string, secret []byte) Key {
+	return Key{ID: kid, Method: jwt.SigningMethodHS256, private: secret, public: secret}
+}
+
+func NewRSAKey(kid string, key *rsa.PrivateKey) Key {
+	return Key{ID: kid, Method: jwt.SigningMethodRS256, private: key, public: &key.PublicKey}
+}
+
+func NewRSAPublicKey(kid string, key *rsa.PublicKey) Key {
+	return Key{ID: kid, Method: jwt.SigningMethodRS256, public: key}
+}
+
+func NewEd25519Key(kid string, key ed25519.PrivateKey) Key {
+	return Key{ID: kid, Method: jwt.SigningMethodEdDSA, private: key, public: key.Public()}
+}
+
+func NewEd25519PublicKey(kid string, key ed25519.PublicKey) Key {
+	return Key{ID: kid, Method: jwt.SigningMethodEdDSA, public: key}
+}
+
+// ParsePEMKey reads a PKCS#8 private key or a PKIX public key, RSA or
+// Ed25519.
+func ParsePEMKey(kid string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("%s: no PEM block", kid)
+	}
+
+	var (
+		parsed any
+		err    error
+	)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("%s: %w: %s", kid, ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %w", kid, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return NewRSAKey(kid, k), nil
+	case *rsa.PublicKey:
+		return NewRSAPublicKey(kid, k), nil
+	case ed25519.PrivateKey:
+		return NewEd25519Key(kid, k), nil
+	case ed25519.PublicKey:
+		return NewEd25519PublicKey(kid, k), nil
+	}
+
+	return Key{}, fmt.Errorf("%s: %w: %T", kid, ErrUnsupportedKey, parsed)
+}
+
+// Keyring signs tokens with one key and verifies them with any of its keys,
+// which is what makes rotation possible: a new signing key is added while
+// the previous one still verifies the tokens it has issued.
+type Keyring struct {
+	signing Key
+	keys    map[string]Key
+	methods []string
+}
+
+func NewKeyring(signing Key, verification ...Key) (*Keyring, error) {
+	if signing.private == nil {
+		return nil, fmt.Errorf("%s: %w", signing.ID, ErrNoSigningKey)
+	}
+
+	k := &Keyring{
+		signing: signing,
+		keys:    make(map[string]Key, len(verification)+1),
+	}
+
+	for _, key := range append([]Key{signing}, verification...) {
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("%s: %w", key.ID, ErrDuplicateKey)
+		}
+		k.keys[key.ID] = key
+
+		alg := key.Method.Alg()
+		if !slices.Contains(k.methods, alg) {
+			k.methods = append(k.methods, alg)
+		}
+	}
+
+	return k, nil
+}
+
+// LoadKeyring builds the keyring from the environment-style configuration:
+// secret is an HS256 key named secretKID, keys is a comma-separated list of
+// kid=path to key files, and signingKID picks the signing key among them.
+// A file that isn't PEM holds an HS256 secret.
+func LoadKeyring(secret, secretKID, keys, signingKID string) (*Keyring, error) {
+	var all []Key
+
+	if secret != "" {
+		all = append(all, NewHMACKey(secretKID, []byte(secret)))
+	}
+
+	for _, spec := range strings.Split(keys, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid key spec %q, want kid=path", spec)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", kid, err)
+		}
+
+		if block, _ := pem.Decode(data); block == nil {
+			all = append(all, NewHMACKey(kid, bytes.TrimSpace(data)))
+			continue
+		}
+
+		key, err := ParsePEMKey(kid, data)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, key)
+	}
+
+	if signingKID == "" {
+		signingKID = secretKID
+	}
+
+	var (
+		signing *Key
+		rest    []Key
+	)
+
+	for i := range all {
+		if all[i].ID == signingKID && signing == nil {
+			signing = &all[i]
+			continue
+		}
+		rest = append(rest, all[i])
+	}
+
+	if signing == nil {
+		return nil, fmt.Errorf("%s: %w", signingKID, ErrUnknownKey)
+	}
+
+	return NewKeyring(*signing, rest...)
+}
+
+// Sign signs the claims with the signing key and names it in the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	token.Header["kid"] = k.signing.ID
+
+	return token.SignedString(k.signing.private)
+}
+
+// Keyfunc picks the verification key by the kid header. The token's alg
+// must be the key's own, so a public key is never used as an HMAC secret.
+func (k *Keyring) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+
+	return key.public, nil
+}
+
+// Methods returns the algorithms of the keyring's keys.
+func (k *Keyring) Methods() []string {
+	return k.methods
+}
+
+// JWK is a public key in the JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the keyring. HMAC keys are secret and are
+// never published.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	kids := slices.Sorted(maps.Keys(k.keys))
+
+	for _, kid := range kids {
+		key := k.keys[kid]
+
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return set
+}
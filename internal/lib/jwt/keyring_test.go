@@ -0,0 +1,263 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"messanger/internal/user"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testKeys struct {
+	hmac   Key
+	rsa    Key
+	rsaPub *rsa.PublicKey
+	ed     Key
+	edPub  ed25519.PublicKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testKeys{
+		hmac:   NewHMACKey("hs", []byte("secret")),
+		rsa:    NewRSAKey("rs", rsaKey),
+		rsaPub: &rsaKey.PublicKey,
+		ed:     NewEd25519Key("ed", edKey),
+		edPub:  edPub,
+	}
+}
+
+func testUser() user.User {
+	return user.User{ID: 42, Name: "Alice", Login: "alice"}
+}
+
+func TestNewKeyring(t *testing.T) {
+	keys := newTestKeys(t)
+
+	tests := []struct {
+		name         string
+		signing      Key
+		verification []Key
+		wantErr      error
+	}{
+		{"signing only", keys.hmac, nil, nil},
+		{"with verification keys", keys.rsa, []Key{keys.hmac, keys.ed}, nil},
+		{"public signing key", NewRSAPublicKey("rs", keys.rsaPub), nil, ErrNoSigningKey},
+		{"duplicate kid", keys.hmac, []Key{NewHMACKey("hs", []byte("other"))}, ErrDuplicateKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.signing, tt.verification...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringParse(t *testing.T) {
+	keys := newTestKeys(t)
+
+	// The keyring after a rotation from the HMAC key to the RSA one.
+	keyring, err := NewKeyring(keys.rsa, keys.hmac, keys.ed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, secret any) string {
+		t.Helper()
+
+		claims := Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    Issuer,
+				Subject:   "42",
+				Audience:  jwt.ClaimStrings{Audience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+			},
+			SessionID: 1,
+		}
+
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+
+		s, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	}
+
+	rsaPubDER, err := x509.MarshalPKIXPublicKey(keys.rsaPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := NewToken(testUser(), 1, keyring, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"signed by the signing key", current, false},
+		{"signed by the previous key", sign(jwt.SigningMethodHS256, "hs", []byte("secret")), false},
+		{"signed by a verification key", sign(jwt.SigningMethodEdDSA, "ed", keys.ed.private), false},
+		{"unknown kid", sign(jwt.SigningMethodHS256, "nope", []byte("secret")), true},
+		{"no kid", sign(jwt.SigningMethodHS256, "", []byte("secret")), true},
+		{"public key as HMAC secret", sign(jwt.SigningMethodHS256, "rs", rsaPubDER), true},
+		{"alg of another key", sign(jwt.SigningMethodRS256, "hs", otherRSA), true},
+		{"kid of another key", sign(jwt.SigningMethodRS256, "rs", otherRSA), true},
+		{"wrong HMAC secret", sign(jwt.SigningMethodHS256, "hs", []byte("guess")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := Parse(tt.token, keyring)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("token is accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			if uid, _ := claims.UserID(); uid != 42 {
+				t.Errorf("user id = %d, want 42", uid)
+			}
+		})
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	keys := newTestKeys(t)
+
+	keyring, err := NewKeyring(keys.rsa, keys.hmac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		method  jwt.SigningMethod
+		kid     any
+		want    any
+		wantErr error
+	}{
+		{"rsa", jwt.SigningMethodRS256, "rs", keys.rsaPub, nil},
+		{"hmac", jwt.SigningMethodHS256, "hs", []byte("secret"), nil},
+		{"unknown kid", jwt.SigningMethodHS256, "nope", nil, ErrUnknownKey},
+		{"kid isn't a string", jwt.SigningMethodHS256, 1, nil, ErrUnknownKey},
+		{"hmac alg with rsa kid", jwt.SigningMethodHS256, "rs", nil, jwt.ErrTokenSignatureInvalid},
+		{"rsa alg with hmac kid", jwt.SigningMethodRS256, "hs", nil, jwt.ErrTokenSignatureInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.New(tt.method)
+			token.Header["kid"] = tt.kid
+
+			got, err := keyring.Keyfunc(token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			switch want := tt.want.(type) {
+			case []byte:
+				if string(got.([]byte)) != string(want) {
+					t.Errorf("key = %v, want %v", got, want)
+				}
+			default:
+				if got != want {
+					t.Errorf("key = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+
+	keyring, err := NewKeyring(keys.rsa, keys.hmac, keys.ed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := keyring.JWKS()
+
+	want := []JWK{
+		{
+			Kty: "OKP",
+			Kid: "ed",
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(keys.edPub),
+		},
+		{
+			Kty: "RSA",
+			Kid: "rs",
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(keys.rsaPub.N.Bytes()),
+			E:   "AQAB",
+		},
+	}
+
+	if len(set.Keys) != len(want) {
+		t.Fatalf("got %d keys, want %d: %+v", len(set.Keys), len(want), set.Keys)
+	}
+
+	for i := range want {
+		if set.Keys[i] != want[i] {
+			t.Errorf("key %d = %+v, want %+v", i, set.Keys[i], want[i])
+		}
+	}
+}
+
+func TestJWKSWithoutPublicKeys(t *testing.T) {
+	keyring, err := NewKeyring(NewHMACKey("hs", []byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := keyring.JWKS()
+	if set.Keys == nil || len(set.Keys) != 0 {
+		t.Errorf("keys = %#v, want an empty list", set.Keys)
+	}
+}
@@ -0,0 +1,66 @@
+// Package httplog logs requests like chi's middleware.Logger, with secrets
+// in the query string masked.
+package httplog
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+const redacted = "REDACTED"
+
+// Logger logs every request with the values of the query params masked.
+// Websocket clients in browsers can't set headers, so they pass their token
+// as ?token=.
+func Logger(params ...string) func(next http.Handler) http.Handler {
+	return middleware.RequestLogger(&formatter{
+		next: &middleware.DefaultLogFormatter{
+			Logger:  log.New(os.Stdout, "", log.LstdFlags),
+			NoColor: runtime.GOOS == "windows",
+		},
+		params: params,
+	})
+}
+
+type formatter struct {
+	next   middleware.LogFormatter
+	params []string
+}
+
+func (f *formatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	return f.next.NewLogEntry(f.redact(r))
+}
+
+// redact returns a copy of r to log, the handlers keep the original.
+func (f *formatter) redact(r *http.Request) *http.Request {
+	if r.URL.RawQuery == "" {
+		return r
+	}
+
+	query := r.URL.Query()
+	masked := false
+
+	for _, param := range f.params {
+		if query.Has(param) {
+			query.Set(param, redacted)
+			masked = true
+		}
+	}
+
+	if !masked {
+		return r
+	}
+
+	u := *r.URL
+	u.RawQuery = query.Encode()
+
+	logged := r.WithContext(r.Context())
+	logged.URL = &u
+	logged.RequestURI = u.RequestURI()
+
+	return logged
+}
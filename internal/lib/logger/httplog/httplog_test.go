@@ -0,0 +1,35 @@
+package httplog
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{name: "no query", target: "/ws", want: "/ws"},
+		{name: "token", target: "/ws?token=secret", want: "/ws?token=REDACTED"},
+		{name: "other params", target: "/chat?limit=10&token=secret", want: "/chat?limit=10&token=REDACTED"},
+		{name: "nothing to mask", target: "/chat?limit=10", want: "/chat?limit=10"},
+	}
+
+	f := &formatter{params: []string{"token"}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+
+			got := f.redact(r)
+			if got.RequestURI != tt.want {
+				t.Errorf("RequestURI = %q, want %q", got.RequestURI, tt.want)
+			}
+			if r.RequestURI != tt.target {
+				t.Errorf("original RequestURI = %q, want %q", r.RequestURI, tt.target)
+			}
+		})
+	}
+}
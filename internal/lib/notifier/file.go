@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// File appends every message as a JSON line to a file, or writes it to the
+// log when the path is empty. It's meant for local development and tests.
+type File struct {
+	log  *slog.Logger
+	path string
+
+	mu sync.Mutex
+}
+
+func NewFile(log *slog.Logger, path string) *File {
+	return &File{
+		log:  log,
+		path: path,
+	}
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	const op = "lib.notifier.file.Send"
+
+	if f.path == "" {
+		f.log.Info("notification",
+			slog.String("op", op),
+			slog.String("to", msg.To),
+			slog.String("subject", msg.Subject),
+			slog.String("body", msg.Body),
+		)
+		return nil
+	}
+
+	line, err := json.Marshal(struct {
+		Message
+		Time time.Time `json:"time"`
+	}{msg, time.Now()})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
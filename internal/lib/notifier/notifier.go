@@ -0,0 +1,14 @@
+package notifier
+
+import "context"
+
+// Message is addressed to an email address.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,170 @@
+package passwd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgBcrypt   = "bcrypt"
+	AlgArgon2id = "argon2id"
+)
+
+var (
+	ErrMismatch       = errors.New("password doesn't match")
+	ErrUnknownAlg     = errors.New("unknown password hash algorithm")
+	ErrMalformedHash  = errors.New("malformed password hash")
+	ErrUnsupportedAlg = errors.New("unsupported password hash algorithm")
+)
+
+// Argon2Params are the Argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2Params follow the second recommended option of RFC 9106.
+var DefaultArgon2Params = Argon2Params{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 4,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes made by either algorithm, so the algorithm can be switched without
+// locking anybody out.
+type Hasher struct {
+	alg        string
+	bcryptCost int
+	argon2     Argon2Params
+}
+
+func NewHasher(alg string, bcryptCost int, argon2 Argon2Params) (*Hasher, error) {
+	switch alg {
+	case AlgBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost %d is out of range", bcryptCost)
+		}
+	case AlgArgon2id:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlg, alg)
+	}
+
+	return &Hasher{
+		alg:        alg,
+		bcryptCost: bcryptCost,
+		argon2:     argon2,
+	}, nil
+}
+
+// Alg returns the algorithm new hashes are made with.
+func (h *Hasher) Alg() string {
+	return h.alg
+}
+
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	if h.alg == AlgBcrypt {
+		return bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	}
+
+	salt := make([]byte, h.argon2.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	p := h.argon2
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return []byte(encoded), nil
+}
+
+// Verify checks the password against the hash. needsRehash reports that the
+// hash has been made with another algorithm or other costs than the current
+// ones.
+func (h *Hasher) Verify(hash []byte, password string) (needsRehash bool, err error) {
+	switch {
+	case bytes.HasPrefix(hash, []byte("$argon2id$")):
+		params, salt, key, err := parseArgon2(string(hash))
+		if err != nil {
+			return false, err
+		}
+
+		got := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, ErrMismatch
+		}
+
+		current := h.argon2
+		current.SaltLen = params.SaltLen
+
+		return h.alg != AlgArgon2id || params != current, nil
+	case bytes.HasPrefix(hash, []byte("$2")):
+		if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrMismatch
+			}
+			return false, err
+		}
+
+		cost, err := bcrypt.Cost(hash)
+		if err != nil {
+			return false, err
+		}
+
+		return h.alg != AlgBcrypt || cost != h.bcryptCost, nil
+	}
+
+	return false, ErrUnsupportedAlg
+}
+
+func parseArgon2(encoded string) (Argon2Params, []byte, []byte, error) {
+	// $argon2id$v=19$m=65536,t=3,p=4$salt$key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,144 @@
+package passwd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keep the tests fast.
+var testArgon2Params = Argon2Params{
+	Memory:  64,
+	Time:    1,
+	Threads: 1,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+func newHasher(t *testing.T, alg string, cost int, params Argon2Params) *Hasher {
+	t.Helper()
+
+	h, err := NewHasher(alg, cost, params)
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+
+	return h
+}
+
+func TestNewHasher(t *testing.T) {
+	tests := []struct {
+		name    string
+		alg     string
+		cost    int
+		wantErr bool
+	}{
+		{"bcrypt", AlgBcrypt, bcrypt.MinCost, false},
+		{"bcrypt cost too low", AlgBcrypt, bcrypt.MinCost - 1, true},
+		{"bcrypt cost too high", AlgBcrypt, bcrypt.MaxCost + 1, true},
+		{"argon2id", AlgArgon2id, 0, false},
+		{"unknown", "md5", bcrypt.MinCost, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHasher(tt.alg, tt.cost, testArgon2Params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	bcryptLow := newHasher(t, AlgBcrypt, bcrypt.MinCost, testArgon2Params)
+	bcryptHigh := newHasher(t, AlgBcrypt, bcrypt.MinCost+1, testArgon2Params)
+	argon := newHasher(t, AlgArgon2id, bcrypt.MinCost, testArgon2Params)
+
+	moreMemory := testArgon2Params
+	moreMemory.Memory *= 2
+	argonMoreMemory := newHasher(t, AlgArgon2id, bcrypt.MinCost, moreMemory)
+
+	bcryptHash, err := bcryptLow.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := argon.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		hasher          *Hasher
+		hash            []byte
+		password        string
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{"bcrypt", bcryptLow, bcryptHash, password, false, nil},
+		{"bcrypt mismatch", bcryptLow, bcryptHash, "wrong", false, ErrMismatch},
+		{"bcrypt other cost", bcryptHigh, bcryptHash, password, true, nil},
+		{"bcrypt hash with argon2id current", argon, bcryptHash, password, true, nil},
+		{"argon2id", argon, argonHash, password, false, nil},
+		{"argon2id mismatch", argon, argonHash, "wrong", false, ErrMismatch},
+		{"argon2id other params", argonMoreMemory, argonHash, password, true, nil},
+		{"argon2id hash with bcrypt current", bcryptLow, argonHash, password, true, nil},
+		{"malformed argon2id", argon, []byte("$argon2id$v=19$broken"), password, false, ErrMalformedHash},
+		{"unsupported", argon, []byte("plain"), password, false, ErrUnsupportedAlg},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needsRehash, err := tt.hasher.Verify(tt.hash, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if needsRehash != tt.wantNeedsRehash {
+				t.Errorf("needsRehash = %v, want %v", needsRehash, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	bcryptPolicy := NewPolicy(8, AlgBcrypt)
+	argonPolicy := NewPolicy(8, AlgArgon2id)
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		wantErr  error
+	}{
+		{"ok", bcryptPolicy, "kettle-mountain-41", nil},
+		{"too short", bcryptPolicy, "short", ErrTooShort},
+		{"runes count for the minimum", bcryptPolicy, "пароль12", nil},
+		{"72 bytes with bcrypt", bcryptPolicy, strings.Repeat("x", 72), nil},
+		{"73 bytes with bcrypt", bcryptPolicy, strings.Repeat("x", 73), ErrTooLong},
+		{"multibyte over 72 bytes with bcrypt", bcryptPolicy, strings.Repeat("ж", 37), ErrTooLong},
+		{"73 bytes with argon2id", argonPolicy, strings.Repeat("x", 73), nil},
+		{"too common", bcryptPolicy, "password", ErrTooCommon},
+		{"too common in other case", bcryptPolicy, "PASSWORD", ErrTooCommon},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				var policyErr *PolicyError
+				if !errors.As(err, &policyErr) {
+					t.Errorf("err = %T, want *PolicyError", err)
+				}
+			}
+		})
+	}
+}
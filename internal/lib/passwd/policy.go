@@ -0,0 +1,88 @@
+package passwd
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is the length after which bcrypt ignores the password.
+const bcryptMaxBytes = 72
+
+var (
+	ErrTooShort  = errors.New("password is too short")
+	ErrTooLong   = errors.New("password is too long")
+	ErrTooCommon = errors.New("password is too common")
+)
+
+//go:embed common.txt
+var commonList string
+
+var common = func() map[string]struct{} {
+	set := make(map[string]struct{})
+
+	sc := bufio.NewScanner(strings.NewReader(commonList))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			set[line] = struct{}{}
+		}
+	}
+
+	return set
+}()
+
+// PolicyError tells which rule a password breaks, it wraps one of the
+// sentinel errors above.
+type PolicyError struct {
+	Err    error
+	Detail string
+}
+
+func (e *PolicyError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+
+	return e.Err.Error() + ": " + e.Detail
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+// Policy is what a new password must satisfy.
+type Policy struct {
+	MinLength int
+	// MaxBytes is bcrypt's limit when hashing with bcrypt, longer passwords
+	// would be silently truncated.
+	MaxBytes int
+}
+
+func NewPolicy(minLength int, alg string) Policy {
+	p := Policy{
+		MinLength: minLength,
+		MaxBytes:  1024,
+	}
+	if alg == AlgBcrypt {
+		p.MaxBytes = bcryptMaxBytes
+	}
+
+	return p
+}
+
+func (p Policy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return &PolicyError{Err: ErrTooShort, Detail: fmt.Sprintf("at least %d characters", p.MinLength)}
+	}
+	if len(password) > p.MaxBytes {
+		return &PolicyError{Err: ErrTooLong, Detail: fmt.Sprintf("at most %d bytes", p.MaxBytes)}
+	}
+	if _, ok := common[strings.ToLower(password)]; ok {
+		return &PolicyError{Err: ErrTooCommon}
+	}
+
+	return nil
+}
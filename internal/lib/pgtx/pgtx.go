@@ -0,0 +1,79 @@
+// Package pgtx carries a Postgres transaction in a context, so that the
+// writes of several repositories, such as a change and the events about it,
+// commit together.
+package pgtx
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Conn is what a pool and a transaction have in common. Begin on a
+// transaction starts a savepoint.
+type Conn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+type state struct {
+	tx          pgx.Tx
+	afterCommit []func()
+}
+
+// Run calls fn with a context that carries a transaction of the pool and
+// commits it if fn succeeds. Within another Run it joins the outer
+// transaction.
+func Run(ctx context.Context, pool *pgxpool.Pool, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*state); ok {
+		return fn(ctx)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	st := &state{tx: tx}
+
+	if err := fn(context.WithValue(ctx, txKey{}, st)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, f := range st.afterCommit {
+		f()
+	}
+
+	return nil
+}
+
+// From returns the transaction of ctx, or the pool outside of one.
+func From(ctx context.Context, pool *pgxpool.Pool) Conn {
+	if st, ok := ctx.Value(txKey{}).(*state); ok {
+		return st.tx
+	}
+
+	return pool
+}
+
+// AfterCommit calls f once the transaction of ctx commits, or right away
+// outside of one. f isn't called if the transaction rolls back.
+func AfterCommit(ctx context.Context, f func()) {
+	if st, ok := ctx.Value(txKey{}).(*state); ok {
+		st.afterCommit = append(st.afterCommit, f)
+		return
+	}
+
+	f()
+}
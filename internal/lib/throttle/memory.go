@@ -0,0 +1,59 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps attempts in the process, it's only good for a single
+// instance of the app.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		entries: make(map[string]Entry),
+	}
+}
+
+func (m *Memory) Add(_ context.Context, key string, now time.Time, policy Policy) (time.Duration, Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entries[key]
+	if wait := policy.Wait(entry, now); wait > 0 {
+		return wait, entry, nil
+	}
+
+	entry = policy.next(entry, now)
+	m.entries[key] = entry
+
+	return 0, entry, nil
+}
+
+func (m *Memory) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+
+	return nil
+}
+
+func (m *Memory) DeleteOlderThan(_ context.Context, t time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for key, entry := range m.entries {
+		if entry.LastAttemptAt.Before(t) {
+			delete(m.entries, key)
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,130 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Postgres keeps attempts in the database, so that they add up across the
+// instances of the app.
+type Postgres struct {
+	// mu serializes the queries, the limiters share the connection.
+	mu sync.Mutex
+	db *pgx.Conn
+}
+
+func NewPostgres(ctx context.Context, dbURL string) (*Postgres, error) {
+	const op = "throttle.postgres.NewPostgres"
+
+	conn, err := pgx.Connect(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Postgres{db: conn}, nil
+}
+
+func (p *Postgres) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.db.Close(ctx)
+}
+
+// Add locks the row of the key, so that the instances of the app count
+// attempts one at a time.
+func (p *Postgres) Add(ctx context.Context, key string, now time.Time, policy Policy) (time.Duration, Entry, error) {
+	const op = "throttle.postgres.Add"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return 0, Entry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO auth_attempts(key, attempts, last_attempt_at) VALUES(@key, 0, @now)
+		ON CONFLICT (key) DO NOTHING`
+	args := pgx.NamedArgs{
+		"key": key,
+		"now": now,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return 0, Entry{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `SELECT attempts, last_attempt_at FROM auth_attempts WHERE key = @key FOR UPDATE`
+
+	var entry Entry
+
+	if err := tx.QueryRow(ctx, sql, args).Scan(&entry.Attempts, &entry.LastAttemptAt); err != nil {
+		return 0, Entry{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if wait := policy.Wait(entry, now); wait > 0 {
+		return wait, entry, nil
+	}
+
+	entry = policy.next(entry, now)
+
+	sql = `UPDATE auth_attempts SET attempts = @attempts, last_attempt_at = @last_attempt_at WHERE key = @key`
+	args = pgx.NamedArgs{
+		"key":             key,
+		"attempts":        entry.Attempts,
+		"last_attempt_at": entry.LastAttemptAt,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return 0, Entry{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, Entry{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return 0, entry, nil
+}
+
+func (p *Postgres) Reset(ctx context.Context, key string) error {
+	const op = "throttle.postgres.Reset"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sql := `DELETE FROM auth_attempts WHERE key = @key`
+	args := pgx.NamedArgs{
+		"key": key,
+	}
+
+	if _, err := p.db.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Postgres) DeleteOlderThan(ctx context.Context, t time.Time) (int64, error) {
+	const op = "throttle.postgres.DeleteOlderThan"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sql := `DELETE FROM auth_attempts WHERE last_attempt_at < @before`
+	args := pgx.NamedArgs{
+		"before": t,
+	}
+
+	tag, err := p.db.Exec(ctx, sql, args)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
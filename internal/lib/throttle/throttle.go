@@ -0,0 +1,181 @@
+// Package throttle slows down repeated attempts, such as password guesses,
+// with an exponential backoff per key.
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"time"
+)
+
+const cleanupPeriod = time.Minute * 10
+
+var ErrThrottled = errors.New("too many attempts")
+
+// Entry is what a store keeps about a key.
+type Entry struct {
+	Attempts      int
+	LastAttemptAt time.Time
+}
+
+// Store keeps attempts of keys. Keys of different limiters are prefixed with
+// the limiter name, so one store can back several limiters.
+type Store interface {
+	// Add counts an attempt made at now unless the key still has to wait
+	// after its previous attempts, and returns the wait. Checking and
+	// counting is atomic, so parallel attempts can't all pass on the same
+	// count. Attempts last tried more than the policy window ago are
+	// forgotten first.
+	Add(ctx context.Context, key string, now time.Time, policy Policy) (time.Duration, Entry, error)
+	Reset(ctx context.Context, key string) error
+	// DeleteOlderThan forgets the keys last tried before t.
+	DeleteOlderThan(ctx context.Context, t time.Time) (int64, error)
+}
+
+// Policy is how many attempts are free and how the delay grows after them.
+// The delay starts at Base and doubles with every attempt up to Max, a key
+// that has reached Max is locked out. Attempts are forgotten Window after the
+// last one.
+type Policy struct {
+	Free   int
+	Base   time.Duration
+	Max    time.Duration
+	Window time.Duration
+}
+
+// Delay is how long to wait after the given number of attempts.
+func (p Policy) Delay(attempts int) time.Duration {
+	if attempts <= p.Free {
+		return 0
+	}
+
+	delay := p.Base
+	for i := p.Free + 1; i < attempts && delay < p.Max; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.Max)
+}
+
+// Wait is how long the entry has to wait at now before its next attempt.
+func (p Policy) Wait(entry Entry, now time.Time) time.Duration {
+	if entry.Attempts == 0 || now.Sub(entry.LastAttemptAt) >= p.Window {
+		return 0
+	}
+
+	until := entry.LastAttemptAt.Add(p.Delay(entry.Attempts))
+
+	return max(until.Sub(now), 0)
+}
+
+// next is the entry after an attempt at now.
+func (p Policy) next(entry Entry, now time.Time) Entry {
+	if now.Sub(entry.LastAttemptAt) >= p.Window {
+		entry.Attempts = 0
+	}
+
+	entry.Attempts++
+	entry.LastAttemptAt = now
+
+	return entry
+}
+
+type Limiter struct {
+	log    *slog.Logger
+	store  Store
+	name   string
+	policy Policy
+}
+
+func New(log *slog.Logger, store Store, name string, policy Policy) *Limiter {
+	return &Limiter{
+		log:    log,
+		store:  store,
+		name:   name,
+		policy: policy,
+	}
+}
+
+// Attempt counts an attempt of the key before it is made. It returns how
+// long the key has to wait instead when it's throttled, the attempt isn't
+// counted then.
+func (l *Limiter) Attempt(ctx context.Context, key string) (time.Duration, error) {
+	const op = "throttle.Limiter.Attempt"
+
+	wait, entry, err := l.store.Add(ctx, l.key(key), now(), l.policy)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if wait == 0 {
+		return 0, nil
+	}
+
+	log := l.log.With(
+		slog.String("op", op),
+		slog.String("limiter", l.name),
+		slog.String("key", key),
+		slog.Int("attempts", entry.Attempts),
+		slog.Duration("retry_after", wait),
+	)
+
+	if l.policy.Delay(entry.Attempts) >= l.policy.Max {
+		log.Warn("locked out", sl.Err(ErrThrottled))
+	} else {
+		log.Info("throttled", sl.Err(ErrThrottled))
+	}
+
+	return wait, nil
+}
+
+// Reset forgets the attempts of the key, after a successful login for one.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	const op = "throttle.Limiter.Reset"
+
+	if err := l.store.Reset(ctx, l.key(key)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (l *Limiter) key(key string) string {
+	return l.name + ":" + key
+}
+
+// RunCleanup periodically deletes keys not tried for longer than retention
+// until ctx is cancelled. retention should be the longest window of the
+// limiters sharing the store.
+func RunCleanup(ctx context.Context, log *slog.Logger, store Store, retention time.Duration) {
+	const op = "throttle.RunCleanup"
+
+	log = log.With(
+		slog.String("op", op),
+	)
+
+	ticker := time.NewTicker(cleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := store.DeleteOlderThan(ctx, now().Add(-retention))
+			if err != nil {
+				log.Error("failed to delete old attempts", sl.Err(err))
+				continue
+			}
+
+			log.Debug("old attempts deleted", slog.Int64("count", n))
+		}
+	}
+}
+
+// now is in UTC, as the times read back from Postgres are.
+func now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,196 @@
+package throttle
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	Free:   2,
+	Base:   time.Minute,
+	Max:    time.Minute * 8,
+	Window: time.Hour,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, time.Minute * 2},
+		{5, time.Minute * 4},
+		{6, time.Minute * 8},
+		{7, time.Minute * 8},
+		{1000, time.Minute * 8},
+	}
+
+	for _, tt := range tests {
+		if got := testPolicy.Delay(tt.attempts); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyWait(t *testing.T) {
+	last := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		entry Entry
+		now   time.Time
+		want  time.Duration
+	}{
+		{"no attempts", Entry{}, last, 0},
+		{"free attempts", Entry{Attempts: 2, LastAttemptAt: last}, last, 0},
+		{"delayed", Entry{Attempts: 3, LastAttemptAt: last}, last.Add(time.Second * 20), time.Second * 40},
+		{"delay is over", Entry{Attempts: 3, LastAttemptAt: last}, last.Add(time.Minute), 0},
+		{"window is over", Entry{Attempts: 10, LastAttemptAt: last}, last.Add(time.Hour), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testPolicy.Wait(tt.entry, tt.now); got != tt.want {
+				t.Errorf("Wait = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryAdd(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		at           time.Duration
+		wantWait     time.Duration
+		wantAttempts int
+	}{
+		{"first free", 0, 0, 1},
+		{"second free", 0, 0, 2},
+		{"third passes on the free count", 0, 0, 3},
+		{"fourth waits and isn't counted", time.Second * 30, time.Second * 30, 3},
+		{"fourth after the delay", time.Minute, 0, 4},
+		{"fifth too early", time.Minute * 2, time.Minute, 4},
+		{"counted from scratch after the window", time.Minute + time.Hour, 0, 1},
+	}
+
+	m := NewMemory()
+
+	for _, tt := range tests {
+		wait, entry, err := m.Add(ctx, "key", start.Add(tt.at), testPolicy)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if wait != tt.wantWait || entry.Attempts != tt.wantAttempts {
+			t.Errorf("%s: Add = (%s, %d attempts), want (%s, %d attempts)",
+				tt.name, wait, entry.Attempts, tt.wantWait, tt.wantAttempts)
+		}
+	}
+}
+
+func TestMemoryDeleteOlderThan(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	m := NewMemory()
+	m.Add(ctx, "old", start, testPolicy)
+	m.Add(ctx, "new", start.Add(time.Hour), testPolicy)
+
+	n, err := m.DeleteOlderThan(ctx, start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("deleted %d keys, want 1", n)
+	}
+	if _, ok := m.entries["new"]; !ok {
+		t.Error("the recent key has been deleted")
+	}
+}
+
+func TestLimiterAttempt(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.DiscardHandler)
+	store := NewMemory()
+
+	login := New(log, store, "login", testPolicy)
+	register := New(log, store, "register", testPolicy)
+
+	for i := range testPolicy.Free + 1 {
+		wait, err := login.Attempt(ctx, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait != 0 {
+			t.Fatalf("attempt %d waits %s, want none", i+1, wait)
+		}
+	}
+
+	wait, err := login.Attempt(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > testPolicy.Base {
+		t.Errorf("throttled attempt waits %s, want up to %s", wait, testPolicy.Base)
+	}
+
+	if wait, _ := login.Attempt(ctx, "bob"); wait != 0 {
+		t.Errorf("another key waits %s", wait)
+	}
+	if wait, _ := register.Attempt(ctx, "alice"); wait != 0 {
+		t.Errorf("the same key of another limiter waits %s", wait)
+	}
+
+	if err := login.Reset(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := login.Attempt(ctx, "alice"); wait != 0 {
+		t.Errorf("attempt after reset waits %s", wait)
+	}
+}
+
+func TestLimiterAttemptParallel(t *testing.T) {
+	ctx := context.Background()
+	l := New(slog.New(slog.DiscardHandler), NewMemory(), "login", testPolicy)
+
+	const attempts = 50
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		passed int
+	)
+
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			wait, err := l.Attempt(ctx, "alice")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// The free attempts and the one that starts the delay.
+	if want := testPolicy.Free + 1; passed != want {
+		t.Errorf("%d of %d parallel attempts passed, want %d", passed, attempts, want)
+	}
+}
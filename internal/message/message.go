@@ -0,0 +1,153 @@
+package message
+
+import "time"
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+	// MaxBulkDelete is how many messages can be deleted in one request.
+	MaxBulkDelete = 100
+	// MaxRecentRepliers is how many of the latest repliers a thread lists.
+	MaxRecentRepliers = 3
+	// MaxReactionKinds is how many different emoji a message can collect.
+	MaxReactionKinds = 20
+	// MaxPins is how many messages a chat can have pinned at once.
+	MaxPins = 50
+)
+
+// Kinds of service messages.
+const (
+	ServicePinned   = "pinned"
+	ServiceUnpinned = "unpinned"
+)
+
+type Message struct {
+	ID           uint64
+	ChatID       uint64
+	AuthorUserID uint64
+	Text         string
+	CreatedAt    time.Time
+	EditedAt     *time.Time
+	// DeletedAt is set on a tombstone, a message deleted for everyone. It
+	// keeps its ID but has no text.
+	DeletedAt *time.Time
+	// ReplyToMsgID is the quoted message, zero if it's not a reply.
+	ReplyToMsgID uint64
+	// ThreadRootID is the top-level message whose thread the message is in,
+	// zero for the chat history.
+	ThreadRootID uint64
+	// Thread summarizes the replies to a top-level message, nil if there are
+	// none or it wasn't looked up.
+	Thread *Thread
+	// Service is the kind of a service message, empty for the ones users
+	// write. A service message has no text and replies to the message it's
+	// about.
+	Service string
+	// Reactions are counted per emoji in the order they first appeared, nil
+	// if they weren't looked up.
+	Reactions []Reaction
+}
+
+// Reaction is the count of an emoji on a message and whether the reader is
+// among those who reacted with it.
+type Reaction struct {
+	MsgID   uint64
+	Emoji   string
+	Count   uint64
+	Reacted bool
+}
+
+func (m Message) Deleted() bool {
+	return m.DeletedAt != nil
+}
+
+// Revision is a version of a message replaced by an edit.
+type Revision struct {
+	ID         uint64
+	MsgID      uint64
+	Text       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+// Thread is the summary of the replies to a top-level message, with the
+// reader's own read marker in it.
+type Thread struct {
+	RootMsgID      uint64
+	ReplyCount     uint64
+	LastReplyMsgID uint64
+	LastReplyAt    time.Time
+	RecentRepliers []uint64
+	LastReadMsgID  uint64
+	UnreadCount    uint64
+}
+
+// Pin is a message pinned in a chat.
+type Pin struct {
+	ChatID   uint64
+	MsgID    uint64
+	PinnedBy uint64
+	PinnedAt time.Time
+	Message  Message
+}
+
+func NewMessage(chatID, authorUserID uint64, text string) Message {
+	return Message{
+		ChatID:       chatID,
+		AuthorUserID: authorUserID,
+		Text:         text,
+	}
+}
+
+// NewServiceMessage makes a service message of the kind about msgID on
+// behalf of the user who made the change.
+func NewServiceMessage(chatID, userID uint64, service string, msgID uint64) Message {
+	return Message{
+		ChatID:       chatID,
+		AuthorUserID: userID,
+		ReplyToMsgID: msgID,
+		Service:      service,
+	}
+}
+
+// Page describes a keyset page of chat history.
+// Before returns messages older than the given id (newest first),
+// After returns messages newer than the given id (oldest first).
+// Only one of them is expected to be set, zero values mean "from the latest".
+type Page struct {
+	Before uint64
+	After  uint64
+	Limit  int
+}
+
+func (p Page) Normalize() Page {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+
+	return p
+}
+
+// ReadState is a member's read marker in a chat and the number of messages
+// from other members after it.
+type ReadState struct {
+	ChatID        uint64
+	LastReadMsgID uint64
+	UnreadCount   uint64
+}
+
+// ThreadReadState is a member's read marker in a thread.
+type ThreadReadState struct {
+	RootMsgID     uint64
+	LastReadMsgID uint64
+	UnreadCount   uint64
+}
+
+// Reader is a member whose read marker is at or past a message.
+type Reader struct {
+	UserID        uint64
+	LastReadMsgID uint64
+}
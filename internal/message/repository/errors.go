@@ -0,0 +1,14 @@
+package repository
+
+import "errors"
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrMemberNotFound  = errors.New("member not found")
+	ErrMemberBanned    = errors.New("user is banned in the chat")
+	ErrChatNotFound    = errors.New("chat not found")
+	ErrTooManyKinds    = errors.New("too many different reactions on the message")
+	ErrAlreadyPinned   = errors.New("message is already pinned")
+	ErrTooManyPins     = errors.New("too many pinned messages in the chat")
+	ErrPinNotFound     = errors.New("message is not pinned")
+)
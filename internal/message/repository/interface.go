@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"messanger/internal/chat"
+	"messanger/internal/message"
+)
+
+type MessageRepo interface {
+	MessageReader
+	MessageWriter
+	MemberReader
+	ReadMarker
+	ThreadReader
+	Reactions
+	Pins
+
+	// InTx runs fn in one transaction, with the events it publishes.
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type MessageReader interface {
+	GetByID(ctx context.Context, id uint64) (message.Message, error)
+	// List skips the messages the user has hidden.
+	List(ctx context.Context, chatID uint64, userID uint64, page message.Page) ([]message.Message, error)
+	// GetMany returns the messages of the chat among ids.
+	GetMany(ctx context.Context, chatID uint64, ids []uint64) ([]message.Message, error)
+	// ListRevisions returns the versions replaced by edits, oldest first.
+	ListRevisions(ctx context.Context, msgID uint64) ([]message.Revision, error)
+}
+
+type MessageWriter interface {
+	Create(ctx context.Context, msg message.Message) (message.Message, error)
+	// Edit replaces the text and keeps the previous one as a revision.
+	Edit(ctx context.Context, id uint64, text string) (message.Message, error)
+	// Hide deletes the messages for the user only.
+	Hide(ctx context.Context, userID uint64, ids []uint64) error
+	// Tombstone deletes the messages for everyone, keeping their IDs. It
+	// unpins them and returns the IDs that were pinned.
+	Tombstone(ctx context.Context, ids []uint64, byUserID uint64) ([]uint64, error)
+}
+
+type MemberReader interface {
+	GetMemberRole(ctx context.Context, userID uint64, chatID uint64) (chat.Role, error)
+	GetChatType(ctx context.Context, chatID uint64) (string, error)
+	// GetAllowedReactions returns nil if the chat allows any reaction.
+	GetAllowedReactions(ctx context.Context, chatID uint64) ([]string, error)
+}
+
+// ReadMarker works with chat_members.last_read_msg_id.
+type ReadMarker interface {
+	// MarkRead moves the marker forward only and returns its resulting value.
+	MarkRead(ctx context.Context, userID uint64, chatID uint64, msgID uint64) (uint64, error)
+	GetReadState(ctx context.Context, userID uint64, chatID uint64) (message.ReadState, error)
+	// ListReaders returns members other than excludeUserID that have read msgID.
+	ListReaders(ctx context.Context, chatID uint64, msgID uint64, excludeUserID uint64) ([]message.Reader, error)
+}
+
+type ThreadReader interface {
+	// ListThread pages over the replies to rootID like List does.
+	ListThread(ctx context.Context, rootID uint64, userID uint64, page message.Page) ([]message.Message, error)
+	// GetThreads summarizes the threads of rootIDs that have replies.
+	GetThreads(ctx context.Context, userID uint64, rootIDs []uint64) ([]message.Thread, error)
+	// MarkThreadRead moves the marker forward only and returns its resulting
+	// value.
+	MarkThreadRead(ctx context.Context, userID uint64, rootID uint64, msgID uint64) (uint64, error)
+}
+
+type Reactions interface {
+	// AddReaction reports whether the reaction is new. It fails with
+	// ErrTooManyKinds when the emoji would exceed maxKinds on the message.
+	AddReaction(ctx context.Context, msgID uint64, userID uint64, emoji string, maxKinds int) (bool, error)
+	// RemoveReaction reports whether there was such a reaction.
+	RemoveReaction(ctx context.Context, msgID uint64, userID uint64, emoji string) (bool, error)
+	// GetReactions counts the reactions to msgIDs per emoji.
+	GetReactions(ctx context.Context, userID uint64, msgIDs []uint64) ([]message.Reaction, error)
+}
+
+// Pins work with chat_pins. Pinning and unpinning post a service message
+// about the change, which they return.
+type Pins interface {
+	// Pin fails with ErrTooManyPins when the chat has maxPins already.
+	Pin(ctx context.Context, pin message.Pin, maxPins int) (message.Pin, message.Message, error)
+	Unpin(ctx context.Context, chatID uint64, msgID uint64, byUserID uint64) (message.Message, error)
+	ListPins(ctx context.Context, chatID uint64) ([]message.Pin, error)
+}
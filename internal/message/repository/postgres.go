@@ -0,0 +1,892 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messanger/internal/chat"
+	"messanger/internal/lib/pgtx"
+	"messanger/internal/message"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const insufficientPrivilege = "42501"
+
+// hiddenFor tells whether the user deleted the message of msgs for
+// themselves.
+const hiddenFor = `EXISTS (SELECT 1 FROM msg_hidden h WHERE h.msg_id = msgs.id AND h.user_id = @user_id)`
+
+// msgColumns are the columns scanMessage reads.
+const msgColumns = `id, chat_id, author_user_id, text, created_at, edited_at, deleted_at,
+	COALESCE(reply_to_msg_id, 0), COALESCE(thread_root_id, 0), COALESCE(service, '')`
+
+// queryRower is either the pool or a transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func New(ctx context.Context, dbURL string) (*Storage, error) {
+	const op = "message.repository.postgres.New"
+
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: pool}, nil
+}
+
+func (s *Storage) Close(ctx context.Context) error {
+	s.db.Close()
+
+	return nil
+}
+
+// InTx runs fn in a transaction. Repositories called with the context fn
+// gets write in that transaction.
+func (s *Storage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return pgtx.Run(ctx, s.db, fn)
+}
+
+// conn is the transaction of ctx if there is one, or the pool.
+func (s *Storage) conn(ctx context.Context) pgtx.Conn {
+	return pgtx.From(ctx, s.db)
+}
+
+func (s *Storage) Create(ctx context.Context, msg message.Message) (message.Message, error) {
+	const op = "message.repository.postgres.Create"
+
+	msg, err := insertMessage(ctx, s.conn(ctx), msg)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msg, nil
+}
+
+// insertMessage inserts msg on db or within a transaction.
+func insertMessage(ctx context.Context, q queryRower, msg message.Message) (message.Message, error) {
+	sql := `INSERT INTO msgs(chat_id, author_user_id, text, reply_to_msg_id, thread_root_id, service)
+		VALUES(@chat_id, @author_user_id, @text, NULLIF(@reply_to_msg_id, 0), NULLIF(@thread_root_id, 0), NULLIF(@service, ''))
+		RETURNING id, created_at;`
+	args := pgx.NamedArgs{
+		"chat_id":         msg.ChatID,
+		"author_user_id":  msg.AuthorUserID,
+		"text":            msg.Text,
+		"reply_to_msg_id": msg.ReplyToMsgID,
+		"thread_root_id":  msg.ThreadRootID,
+		"service":         msg.Service,
+	}
+
+	if err := q.QueryRow(ctx, sql, args).Scan(&msg.ID, &msg.CreatedAt); err != nil {
+		// raised by the msgs_reject_banned trigger
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == insufficientPrivilege {
+			return message.Message{}, ErrMemberBanned
+		}
+
+		return message.Message{}, err
+	}
+
+	return msg, nil
+}
+
+func (s *Storage) GetByID(ctx context.Context, id uint64) (message.Message, error) {
+	const op = "message.repository.postgres.GetByID"
+
+	sql := `SELECT ` + msgColumns + ` FROM msgs WHERE id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	msg, err := scanMessage(s.conn(ctx).QueryRow(ctx, sql, args))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return message.Message{}, fmt.Errorf("%s: %w", op, ErrMessageNotFound)
+		}
+
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msg, nil
+}
+
+// Edit keeps the current text as a revision and replaces it.
+func (s *Storage) Edit(ctx context.Context, id uint64, text string) (message.Message, error) {
+	const op = "message.repository.postgres.Edit"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO msg_revisions(msg_id, text, created_at)
+		SELECT id, text, COALESCE(edited_at, created_at) FROM msgs WHERE id = @id AND deleted_at IS NULL
+		FOR UPDATE`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	tag, err := tx.Exec(ctx, sql, args)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrMessageNotFound)
+	}
+
+	sql = `UPDATE msgs SET text = @text, edited_at = current_timestamp WHERE id = @id
+		RETURNING ` + msgColumns
+	args = pgx.NamedArgs{
+		"id":   id,
+		"text": text,
+	}
+
+	msg, err := scanMessage(tx.QueryRow(ctx, sql, args))
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msg, nil
+}
+
+// ListRevisions returns the replaced versions of a message, oldest first.
+func (s *Storage) ListRevisions(ctx context.Context, msgID uint64) ([]message.Revision, error) {
+	const op = "message.repository.postgres.ListRevisions"
+
+	sql := `SELECT id, msg_id, text, created_at, replaced_at FROM msg_revisions
+		WHERE msg_id = @msg_id
+		ORDER BY id`
+	args := pgx.NamedArgs{
+		"msg_id": msgID,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	revisions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (message.Revision, error) {
+		var rev message.Revision
+		err := row.Scan(&rev.ID, &rev.MsgID, &rev.Text, &rev.CreatedAt, &rev.ReplacedAt)
+		return rev, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revisions, nil
+}
+
+// List returns a keyset page of chat history using idx_msgs_chat_id_id.
+// Tombstones are listed, the messages the user has hidden and thread replies
+// are not.
+func (s *Storage) List(ctx context.Context, chatID uint64, userID uint64, page message.Page) ([]message.Message, error) {
+	const op = "message.repository.postgres.List"
+
+	var sql string
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+		"user_id": userID,
+		"limit":   page.Limit,
+	}
+
+	switch {
+	case page.After != 0:
+		sql = `SELECT ` + msgColumns + ` FROM msgs
+			WHERE chat_id = @chat_id AND thread_root_id IS NULL AND id > @after AND NOT ` + hiddenFor + `
+			ORDER BY id ASC LIMIT @limit`
+		args["after"] = page.After
+	case page.Before != 0:
+		sql = `SELECT ` + msgColumns + ` FROM msgs
+			WHERE chat_id = @chat_id AND thread_root_id IS NULL AND id < @before AND NOT ` + hiddenFor + `
+			ORDER BY id DESC LIMIT @limit`
+		args["before"] = page.Before
+	default:
+		sql = `SELECT ` + msgColumns + ` FROM msgs
+			WHERE chat_id = @chat_id AND thread_root_id IS NULL AND NOT ` + hiddenFor + `
+			ORDER BY id DESC LIMIT @limit`
+	}
+
+	msgs, err := s.query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msgs, nil
+}
+
+// GetMany returns the messages of the chat among ids, the others are
+// skipped.
+func (s *Storage) GetMany(ctx context.Context, chatID uint64, ids []uint64) ([]message.Message, error) {
+	const op = "message.repository.postgres.GetMany"
+
+	sql := `SELECT ` + msgColumns + ` FROM msgs
+		WHERE chat_id = @chat_id AND id = ANY(@ids)
+		ORDER BY id`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+		"ids":     ids,
+	}
+
+	msgs, err := s.query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msgs, nil
+}
+
+func (s *Storage) Hide(ctx context.Context, userID uint64, ids []uint64) error {
+	const op = "message.repository.postgres.Hide"
+
+	sql := `INSERT INTO msg_hidden(user_id, msg_id) SELECT @user_id, unnest(@ids::BIGINT[])
+		ON CONFLICT DO NOTHING`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"ids":     ids,
+	}
+
+	if _, err := s.conn(ctx).Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Tombstone deletes the messages for everyone: the text, the revisions, the
+// reactions and the pins are dropped, the rows stay.
+func (s *Storage) Tombstone(ctx context.Context, ids []uint64, byUserID uint64) ([]uint64, error) {
+	const op = "message.repository.postgres.Tombstone"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE msgs SET text = '', deleted_at = current_timestamp, deleted_by = @by
+		WHERE id = ANY(@ids) AND deleted_at IS NULL`
+	args := pgx.NamedArgs{
+		"ids": ids,
+		"by":  byUserID,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `DELETE FROM msg_revisions WHERE msg_id = ANY(@ids)`
+	args = pgx.NamedArgs{
+		"ids": ids,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `DELETE FROM msg_reactions WHERE msg_id = ANY(@ids)`
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `DELETE FROM chat_pins WHERE msg_id = ANY(@ids) RETURNING msg_id`
+
+	rows, err := tx.Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	unpinned, err := pgx.CollectRows(rows, pgx.RowTo[uint64])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return unpinned, nil
+}
+
+func (s *Storage) GetMemberRole(ctx context.Context, userID uint64, chatID uint64) (chat.Role, error) {
+	const op = "message.repository.postgres.GetMemberRole"
+
+	sql := `SELECT role FROM chat_members WHERE user_id = @user_id AND chat_id = @chat_id AND NOT is_banned`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"chat_id": chatID,
+	}
+
+	var role chat.Role
+
+	if err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(&role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return role, nil
+}
+
+func (s *Storage) GetChatType(ctx context.Context, chatID uint64) (string, error) {
+	const op = "message.repository.postgres.GetChatType"
+
+	sql := `SELECT type FROM chats WHERE id = @id`
+	args := pgx.NamedArgs{
+		"id": chatID,
+	}
+
+	var typ string
+
+	if err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(&typ); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, ErrChatNotFound)
+		}
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return typ, nil
+}
+
+func (s *Storage) GetAllowedReactions(ctx context.Context, chatID uint64) ([]string, error) {
+	const op = "message.repository.postgres.GetAllowedReactions"
+
+	sql := `SELECT allowed_reactions FROM chats WHERE id = @id`
+	args := pgx.NamedArgs{
+		"id": chatID,
+	}
+
+	var allowed []string
+
+	if err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(&allowed); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrChatNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return allowed, nil
+}
+
+func (s *Storage) MarkRead(ctx context.Context, userID uint64, chatID uint64, msgID uint64) (uint64, error) {
+	const op = "message.repository.postgres.MarkRead"
+
+	sql := `UPDATE chat_members SET last_read_msg_id = GREATEST(COALESCE(last_read_msg_id, 0), @msg_id)
+		WHERE chat_id = @chat_id AND user_id = @user_id AND NOT is_banned
+		RETURNING last_read_msg_id`
+	args := pgx.NamedArgs{
+		"msg_id":  msgID,
+		"chat_id": chatID,
+		"user_id": userID,
+	}
+
+	var marker uint64
+
+	if err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(&marker); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return marker, nil
+}
+
+// GetReadState counts unread messages with idx_msgs_chat_id_id, own, service,
+// deleted and hidden messages are never unread. Thread replies have markers of their
+// own.
+func (s *Storage) GetReadState(ctx context.Context, userID uint64, chatID uint64) (message.ReadState, error) {
+	const op = "message.repository.postgres.GetReadState"
+
+	sql := `SELECT cm.chat_id, COALESCE(cm.last_read_msg_id, 0), (
+			SELECT count(*) FROM msgs m
+			WHERE m.chat_id = cm.chat_id
+				AND m.thread_root_id IS NULL
+				AND m.id > COALESCE(cm.last_read_msg_id, 0)
+				AND m.author_user_id <> cm.user_id
+				AND m.service IS NULL
+				AND m.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM msg_hidden h WHERE h.msg_id = m.id AND h.user_id = cm.user_id)
+		)
+		FROM chat_members cm
+		WHERE cm.chat_id = @chat_id AND cm.user_id = @user_id AND NOT cm.is_banned`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+		"user_id": userID,
+	}
+
+	var state message.ReadState
+
+	err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(
+		&state.ChatID,
+		&state.LastReadMsgID,
+		&state.UnreadCount,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return message.ReadState{}, fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+		}
+
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return state, nil
+}
+
+func (s *Storage) ListReaders(ctx context.Context, chatID uint64, msgID uint64, excludeUserID uint64) ([]message.Reader, error) {
+	const op = "message.repository.postgres.ListReaders"
+
+	sql := `SELECT user_id, last_read_msg_id FROM chat_members
+		WHERE chat_id = @chat_id AND NOT is_banned AND last_read_msg_id >= @msg_id AND user_id <> @exclude
+		ORDER BY user_id`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+		"msg_id":  msgID,
+		"exclude": excludeUserID,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	readers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (message.Reader, error) {
+		var reader message.Reader
+		err := row.Scan(&reader.UserID, &reader.LastReadMsgID)
+		return reader, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return readers, nil
+}
+
+// ListThread returns a keyset page of the replies to rootID using
+// idx_msgs_thread_root_id_id, the same way List pages the chat history.
+func (s *Storage) ListThread(ctx context.Context, rootID uint64, userID uint64, page message.Page) ([]message.Message, error) {
+	const op = "message.repository.postgres.ListThread"
+
+	var sql string
+	args := pgx.NamedArgs{
+		"root_id": rootID,
+		"user_id": userID,
+		"limit":   page.Limit,
+	}
+
+	switch {
+	case page.After != 0:
+		sql = `SELECT ` + msgColumns + ` FROM msgs
+			WHERE thread_root_id = @root_id AND id > @after AND NOT ` + hiddenFor + `
+			ORDER BY id ASC LIMIT @limit`
+		args["after"] = page.After
+	case page.Before != 0:
+		sql = `SELECT ` + msgColumns + ` FROM msgs
+			WHERE thread_root_id = @root_id AND id < @before AND NOT ` + hiddenFor + `
+			ORDER BY id DESC LIMIT @limit`
+		args["before"] = page.Before
+	default:
+		sql = `SELECT ` + msgColumns + ` FROM msgs
+			WHERE thread_root_id = @root_id AND NOT ` + hiddenFor + `
+			ORDER BY id DESC LIMIT @limit`
+	}
+
+	msgs, err := s.query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msgs, nil
+}
+
+// GetThreads summarizes the threads of rootIDs for the user. Roots without
+// replies are skipped. Tombstones are not counted, hidden replies are only
+// left out of the unread count.
+func (s *Storage) GetThreads(ctx context.Context, userID uint64, rootIDs []uint64) ([]message.Thread, error) {
+	const op = "message.repository.postgres.GetThreads"
+
+	sql := `SELECT r.thread_root_id, count(*), max(r.id), max(r.created_at),
+			ARRAY(
+				SELECT a.author_user_id FROM msgs a
+				WHERE a.thread_root_id = r.thread_root_id AND a.deleted_at IS NULL
+				GROUP BY a.author_user_id
+				ORDER BY max(a.id) DESC LIMIT @repliers
+			),
+			COALESCE(tr.last_read_msg_id, 0),
+			count(*) FILTER (
+				WHERE r.id > COALESCE(tr.last_read_msg_id, 0) AND r.author_user_id <> @user_id AND h.msg_id IS NULL
+			)
+		FROM msgs r
+		LEFT JOIN thread_reads tr ON tr.root_msg_id = r.thread_root_id AND tr.user_id = @user_id
+		LEFT JOIN msg_hidden h ON h.msg_id = r.id AND h.user_id = @user_id
+		WHERE r.thread_root_id = ANY(@ids) AND r.deleted_at IS NULL
+		GROUP BY r.thread_root_id, tr.last_read_msg_id`
+	args := pgx.NamedArgs{
+		"user_id":  userID,
+		"ids":      rootIDs,
+		"repliers": message.MaxRecentRepliers,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	threads, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (message.Thread, error) {
+		var t message.Thread
+		err := row.Scan(
+			&t.RootMsgID,
+			&t.ReplyCount,
+			&t.LastReplyMsgID,
+			&t.LastReplyAt,
+			&t.RecentRepliers,
+			&t.LastReadMsgID,
+			&t.UnreadCount,
+		)
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return threads, nil
+}
+
+// MarkThreadRead moves the user's marker in the thread forward only and
+// returns its resulting value.
+func (s *Storage) MarkThreadRead(ctx context.Context, userID uint64, rootID uint64, msgID uint64) (uint64, error) {
+	const op = "message.repository.postgres.MarkThreadRead"
+
+	sql := `INSERT INTO thread_reads(user_id, root_msg_id, last_read_msg_id) VALUES(@user_id, @root_id, @msg_id)
+		ON CONFLICT (user_id, root_msg_id) DO UPDATE
+			SET last_read_msg_id = GREATEST(thread_reads.last_read_msg_id, EXCLUDED.last_read_msg_id)
+		RETURNING last_read_msg_id`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"root_id": rootID,
+		"msg_id":  msgID,
+	}
+
+	var marker uint64
+
+	if err := s.conn(ctx).QueryRow(ctx, sql, args).Scan(&marker); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return marker, nil
+}
+
+// AddReaction only inserts an emoji new to the message while it has fewer
+// than maxKinds different ones.
+func (s *Storage) AddReaction(ctx context.Context, msgID uint64, userID uint64, emoji string, maxKinds int) (bool, error) {
+	const op = "message.repository.postgres.AddReaction"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locks the message row so concurrent reactions can't both pass the
+	// limit with a new emoji. The reactions are counted by the next
+	// statement, which sees what the previous holder of the lock committed.
+	sql := `SELECT 1 FROM msgs WHERE id = @msg_id FOR UPDATE`
+	args := pgx.NamedArgs{
+		"msg_id":  msgID,
+		"user_id": userID,
+		"emoji":   emoji,
+	}
+
+	tag, err := tx.Exec(ctx, sql, args)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, fmt.Errorf("%s: %w", op, ErrMessageNotFound)
+	}
+
+	sql = `SELECT
+			EXISTS (SELECT 1 FROM msg_reactions WHERE msg_id = @msg_id AND user_id = @user_id AND emoji = @emoji),
+			EXISTS (SELECT 1 FROM msg_reactions WHERE msg_id = @msg_id AND emoji = @emoji),
+			count(DISTINCT emoji)
+		FROM msg_reactions
+		WHERE msg_id = @msg_id`
+
+	var (
+		reacted, known bool
+		kinds          int
+	)
+
+	if err := tx.QueryRow(ctx, sql, args).Scan(&reacted, &known, &kinds); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if reacted {
+		return false, nil
+	}
+
+	if !known && kinds >= maxKinds {
+		return false, fmt.Errorf("%s: %w", op, ErrTooManyKinds)
+	}
+
+	sql = `INSERT INTO msg_reactions(msg_id, user_id, emoji) VALUES(@msg_id, @user_id, @emoji)`
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+func (s *Storage) RemoveReaction(ctx context.Context, msgID uint64, userID uint64, emoji string) (bool, error) {
+	const op = "message.repository.postgres.RemoveReaction"
+
+	sql := `DELETE FROM msg_reactions WHERE msg_id = @msg_id AND user_id = @user_id AND emoji = @emoji`
+	args := pgx.NamedArgs{
+		"msg_id":  msgID,
+		"user_id": userID,
+		"emoji":   emoji,
+	}
+
+	tag, err := s.conn(ctx).Exec(ctx, sql, args)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetReactions orders the emoji of a message by their first reaction.
+func (s *Storage) GetReactions(ctx context.Context, userID uint64, msgIDs []uint64) ([]message.Reaction, error) {
+	const op = "message.repository.postgres.GetReactions"
+
+	sql := `SELECT msg_id, emoji, count(*), bool_or(user_id = @user_id) FROM msg_reactions
+		WHERE msg_id = ANY(@ids)
+		GROUP BY msg_id, emoji
+		ORDER BY msg_id, min(created_at), emoji`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"ids":     msgIDs,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reactions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (message.Reaction, error) {
+		var r message.Reaction
+		err := row.Scan(&r.MsgID, &r.Emoji, &r.Count, &r.Reacted)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reactions, nil
+}
+
+// Pin pins the message and posts the service message about it in one
+// transaction.
+func (s *Storage) Pin(ctx context.Context, pin message.Pin, maxPins int) (message.Pin, message.Message, error) {
+	const op = "message.repository.postgres.Pin"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locks the chat row so concurrent pins can't both pass the limit.
+	sql := `SELECT (SELECT count(*) FROM chat_pins WHERE chat_id = c.id) FROM chats c
+		WHERE c.id = @chat_id
+		FOR UPDATE`
+	args := pgx.NamedArgs{
+		"chat_id": pin.ChatID,
+	}
+
+	var pins int
+
+	if err := tx.QueryRow(ctx, sql, args).Scan(&pins); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, ErrChatNotFound)
+		}
+
+		return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if pins >= maxPins {
+		return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, ErrTooManyPins)
+	}
+
+	sql = `INSERT INTO chat_pins(chat_id, msg_id, pinned_by) VALUES(@chat_id, @msg_id, @pinned_by)
+		ON CONFLICT DO NOTHING
+		RETURNING pinned_at`
+	args = pgx.NamedArgs{
+		"chat_id":   pin.ChatID,
+		"msg_id":    pin.MsgID,
+		"pinned_by": pin.PinnedBy,
+	}
+
+	if err := tx.QueryRow(ctx, sql, args).Scan(&pin.PinnedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, ErrAlreadyPinned)
+		}
+
+		return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	service, err := insertMessage(ctx, tx, message.NewServiceMessage(pin.ChatID, pin.PinnedBy, message.ServicePinned, pin.MsgID))
+	if err != nil {
+		return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return message.Pin{}, message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pin, service, nil
+}
+
+// Unpin unpins the message and posts the service message about it in one
+// transaction.
+func (s *Storage) Unpin(ctx context.Context, chatID uint64, msgID uint64, byUserID uint64) (message.Message, error) {
+	const op = "message.repository.postgres.Unpin"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `DELETE FROM chat_pins WHERE chat_id = @chat_id AND msg_id = @msg_id`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+		"msg_id":  msgID,
+	}
+
+	tag, err := tx.Exec(ctx, sql, args)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrPinNotFound)
+	}
+
+	service, err := insertMessage(ctx, tx, message.NewServiceMessage(chatID, byUserID, message.ServiceUnpinned, msgID))
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return service, nil
+}
+
+// ListPins returns the pins of the chat with their messages, the latest pin
+// first.
+func (s *Storage) ListPins(ctx context.Context, chatID uint64) ([]message.Pin, error) {
+	const op = "message.repository.postgres.ListPins"
+
+	sql := `SELECT p.chat_id, p.msg_id, COALESCE(p.pinned_by, 0), p.pinned_at,
+			m.id, m.chat_id, m.author_user_id, m.text, m.created_at, m.edited_at, m.deleted_at,
+			COALESCE(m.reply_to_msg_id, 0), COALESCE(m.thread_root_id, 0), COALESCE(m.service, '')
+		FROM chat_pins p
+		JOIN msgs m ON m.id = p.msg_id
+		WHERE p.chat_id = @chat_id AND m.deleted_at IS NULL
+		ORDER BY p.pinned_at DESC, p.msg_id DESC`
+	args := pgx.NamedArgs{
+		"chat_id": chatID,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pins, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (message.Pin, error) {
+		var pin message.Pin
+		msg := &pin.Message
+		err := row.Scan(
+			&pin.ChatID,
+			&pin.MsgID,
+			&pin.PinnedBy,
+			&pin.PinnedAt,
+			&msg.ID,
+			&msg.ChatID,
+			&msg.AuthorUserID,
+			&msg.Text,
+			&msg.CreatedAt,
+			&msg.EditedAt,
+			&msg.DeletedAt,
+			&msg.ReplyToMsgID,
+			&msg.ThreadRootID,
+			&msg.Service,
+		)
+		return pin, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pins, nil
+}
+
+// query runs a query selecting msgColumns.
+func (s *Storage) query(ctx context.Context, sql string, args pgx.NamedArgs) ([]message.Message, error) {
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (message.Message, error) {
+		return scanMessage(row)
+	})
+}
+
+func scanMessage(row pgx.Row) (message.Message, error) {
+	var msg message.Message
+
+	err := row.Scan(
+		&msg.ID,
+		&msg.ChatID,
+		&msg.AuthorUserID,
+		&msg.Text,
+		&msg.CreatedAt,
+		&msg.EditedAt,
+		&msg.DeletedAt,
+		&msg.ReplyToMsgID,
+		&msg.ThreadRootID,
+		&msg.Service,
+	)
+
+	return msg, err
+}
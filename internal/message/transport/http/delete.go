@@ -0,0 +1,92 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+)
+
+// Delete deletes one message, for the caller only unless ?scope=everyone.
+func (h *MessageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	forEveryone, err := parseScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.msgUC.Delete(r.Context(), uid, chatID, []uint64{msgID}, forEveryone)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(DeleteMessagesResDTO{Deleted: deleted})
+}
+
+// DeleteMany deletes a selection of messages at once.
+func (h *MessageHandler) DeleteMany(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.DeleteMany"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var deleteDTO DeleteMessagesReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&deleteDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := deleteDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	forEveryone, _ := parseScope(deleteDTO.Scope)
+
+	deleted, err := h.msgUC.Delete(r.Context(), uid, chatID, deleteDTO.IDs, forEveryone)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(DeleteMessagesResDTO{Deleted: deleted})
+}
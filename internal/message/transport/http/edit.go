@@ -0,0 +1,100 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+)
+
+func (h *MessageHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.Edit"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var editDTO EditMessageReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&editDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := editDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.msgUC.Edit(r.Context(), uid, chatID, msgID, editDTO.Text)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewMessageResDTO(msg))
+}
+
+func (h *MessageHandler) Revisions(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.msgUC.Revisions(r.Context(), uid, chatID, msgID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	resp := RevisionsResDTO{
+		Revisions: make([]RevisionResDTO, 0, len(revisions)),
+	}
+	for _, rev := range revisions {
+		resp.Revisions = append(resp.Revisions, RevisionResDTO{
+			ID:         rev.ID,
+			Text:       rev.Text,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: rev.ReplacedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/auth"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message/repository"
+	"messanger/internal/message/usecase"
+	"net/http"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+type MessageHandler struct {
+	log   *slog.Logger
+	msgUC usecase.MessageUC
+}
+
+func New(log *slog.Logger, msgUC usecase.MessageUC) *MessageHandler {
+	return &MessageHandler{
+		log:   log,
+		msgUC: msgUC,
+	}
+}
+
+func (h *MessageHandler) Send(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.Send"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var sendDTO SendMessageReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&sendDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := sendDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.msgUC.Send(r.Context(), uid, chatID, sendDTO.Text, sendDTO.ReplyToMsgID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(NewMessageResDTO(msg))
+}
+
+func (h *MessageHandler) History(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := ParsePage(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgs, hasMore, err := h.msgUC.History(r.Context(), uid, chatID, page)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	resp := HistoryResDTO{
+		Messages: make([]MessageResDTO, 0, len(msgs)),
+		HasMore:  hasMore,
+	}
+	for _, msg := range msgs {
+		resp.Messages = append(resp.Messages, NewMessageResDTO(msg))
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// userID returns the acting user put into the context by the auth middleware.
+func (h *MessageHandler) userID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		errDTO := NewErrorDTO(ErrInvalidToken)
+		http.Error(w, errDTO.String(), http.StatusUnauthorized)
+		return 0, false
+	}
+
+	return principal.UserID, true
+}
+
+func (h *MessageHandler) writeUCError(w http.ResponseWriter, err error) {
+	var status int
+
+	switch {
+	case errors.Is(err, usecase.ErrNotChatMember):
+		err, status = usecase.ErrNotChatMember, http.StatusForbidden
+	case errors.Is(err, usecase.ErrForbidden):
+		err, status = usecase.ErrForbidden, http.StatusForbidden
+	case errors.Is(err, repository.ErrMemberBanned):
+		err, status = repository.ErrMemberBanned, http.StatusForbidden
+	case errors.Is(err, repository.ErrMessageNotFound):
+		err, status = repository.ErrMessageNotFound, http.StatusNotFound
+	case errors.Is(err, repository.ErrChatNotFound):
+		err, status = repository.ErrChatNotFound, http.StatusNotFound
+	case errors.Is(err, usecase.ErrNotAuthor):
+		err, status = usecase.ErrNotAuthor, http.StatusForbidden
+	case errors.Is(err, usecase.ErrEditWindowExpired):
+		err, status = usecase.ErrEditWindowExpired, http.StatusConflict
+	case errors.Is(err, usecase.ErrDeleteWindowExpired):
+		err, status = usecase.ErrDeleteWindowExpired, http.StatusConflict
+	case errors.Is(err, usecase.ErrMessageDeleted):
+		err, status = usecase.ErrMessageDeleted, http.StatusConflict
+	case errors.Is(err, usecase.ErrThreadsUnavailable):
+		err, status = usecase.ErrThreadsUnavailable, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrNotThreadRoot):
+		err, status = usecase.ErrNotThreadRoot, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrInThread):
+		err, status = usecase.ErrInThread, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrNotInThread):
+		err, status = usecase.ErrNotInThread, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrReactionNotAllowed):
+		err, status = usecase.ErrReactionNotAllowed, http.StatusBadRequest
+	case errors.Is(err, repository.ErrTooManyKinds):
+		err, status = repository.ErrTooManyKinds, http.StatusConflict
+	case errors.Is(err, usecase.ErrServiceMessage):
+		err, status = usecase.ErrServiceMessage, http.StatusConflict
+	case errors.Is(err, repository.ErrAlreadyPinned):
+		err, status = repository.ErrAlreadyPinned, http.StatusConflict
+	case errors.Is(err, repository.ErrTooManyPins):
+		err, status = repository.ErrTooManyPins, http.StatusConflict
+	case errors.Is(err, repository.ErrPinNotFound):
+		err, status = repository.ErrPinNotFound, http.StatusNotFound
+	case errors.Is(err, usecase.ErrReadByUnavailable):
+		err, status = usecase.ErrReadByUnavailable, http.StatusBadRequest
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	errDTO := NewErrorDTO(err)
+	http.Error(w, errDTO.String(), status)
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+)
+
+func (h *MessageHandler) Pin(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.Pin"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var pinDTO PinMessageReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&pinDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := pinDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	pin, err := h.msgUC.Pin(r.Context(), uid, chatID, pinDTO.MsgID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(NewPinResDTO(pin))
+}
+
+func (h *MessageHandler) Unpin(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.msgUC.Unpin(r.Context(), uid, chatID, msgID); err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Pins lists the pinned messages of the chat, the latest pin first.
+func (h *MessageHandler) Pins(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	pins, err := h.msgUC.Pins(r.Context(), uid, chatID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	resp := PinsResDTO{
+		Pins: make([]PinResDTO, 0, len(pins)),
+	}
+	for _, pin := range pins {
+		resp.Pins = append(resp.Pins, NewPinResDTO(pin))
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+)
+
+func (h *MessageHandler) React(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.React"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var reactDTO ReactReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&reactDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := reactDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	reactions, err := h.msgUC.React(r.Context(), uid, chatID, msgID, reactDTO.Emoji)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewReactionsResDTO(reactions))
+}
+
+// Unreact removes the reaction given by ?emoji=, the emoji is in the query
+// rather than the path so it needs no escaping rules of its own.
+func (h *MessageHandler) Unreact(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	reactDTO := ReactReqDTO{Emoji: r.URL.Query().Get("emoji")}
+
+	if err := reactDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	reactions, err := h.msgUC.Unreact(r.Context(), uid, chatID, msgID, reactDTO.Emoji)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewReactionsResDTO(reactions))
+}
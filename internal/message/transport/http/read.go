@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+)
+
+func (h *MessageHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.MarkRead"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var markReadDTO MarkReadReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&markReadDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := markReadDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.msgUC.MarkRead(r.Context(), uid, chatID, markReadDTO.MsgID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewReadStateResDTO(state))
+}
+
+func (h *MessageHandler) ReadState(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.msgUC.ReadState(r.Context(), uid, chatID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewReadStateResDTO(state))
+}
+
+func (h *MessageHandler) ReadBy(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msgID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	readers, err := h.msgUC.ReadBy(r.Context(), uid, chatID, msgID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	resp := ReadByResDTO{
+		Readers: make([]ReaderResDTO, 0, len(readers)),
+	}
+	for _, reader := range readers {
+		resp.Readers = append(resp.Readers, ReaderResDTO{
+			UserID:        reader.UserID,
+			LastReadMsgID: reader.LastReadMsgID,
+		})
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,207 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"messanger/internal/lib/emoji"
+	"messanger/internal/message"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxTextLength = 4096
+
+var (
+	ErrTextIsEmpty      = errors.New("text is empty")
+	ErrTextIsTooLong    = errors.New("text is too long")
+	ErrInvalidChatID    = errors.New("invalid chat id")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrBothCursorsGiven = errors.New("only one of before and after can be set")
+	ErrInvalidMsgID     = errors.New("invalid message id")
+	ErrMsgIdIsEmpty     = errors.New("msg_id is empty")
+	ErrIDsIsEmpty       = errors.New("ids is empty")
+	ErrTooManyIDs       = errors.New("too many ids")
+	ErrInvalidScope     = errors.New("scope must be me or everyone")
+	ErrInvalidEmoji     = errors.New("reaction must be an emoji")
+)
+
+const (
+	ScopeMe       = "me"
+	ScopeEveryone = "everyone"
+)
+
+type SendMessageReqDTO struct {
+	Text         string `json:"text"`
+	ReplyToMsgID uint64 `json:"reply_to_msg_id,omitempty"`
+}
+
+func (s SendMessageReqDTO) Validate() error {
+	return validateText(s.Text)
+}
+
+type EditMessageReqDTO struct {
+	Text string `json:"text"`
+}
+
+func (e EditMessageReqDTO) Validate() error {
+	return validateText(e.Text)
+}
+
+func validateText(text string) error {
+	if text == "" {
+		return ErrTextIsEmpty
+	}
+	if utf8.RuneCountInString(text) > maxTextLength {
+		return ErrTextIsTooLong
+	}
+
+	return nil
+}
+
+type MarkReadReqDTO struct {
+	MsgID uint64 `json:"msg_id"`
+}
+
+func (d MarkReadReqDTO) Validate() error {
+	if d.MsgID == 0 {
+		return ErrMsgIdIsEmpty
+	}
+
+	return nil
+}
+
+type PinMessageReqDTO struct {
+	MsgID uint64 `json:"msg_id"`
+}
+
+func (d PinMessageReqDTO) Validate() error {
+	if d.MsgID == 0 {
+		return ErrMsgIdIsEmpty
+	}
+
+	return nil
+}
+
+type ReactReqDTO struct {
+	Emoji string `json:"emoji"`
+}
+
+func (d ReactReqDTO) Validate() error {
+	if !emoji.Valid(d.Emoji) {
+		return ErrInvalidEmoji
+	}
+
+	return nil
+}
+
+type DeleteMessagesReqDTO struct {
+	IDs   []uint64 `json:"ids"`
+	Scope string   `json:"scope"`
+}
+
+func (d DeleteMessagesReqDTO) Validate() error {
+	if len(d.IDs) == 0 {
+		return ErrIDsIsEmpty
+	}
+	if len(d.IDs) > message.MaxBulkDelete {
+		return ErrTooManyIDs
+	}
+	if slices.Contains(d.IDs, 0) {
+		return ErrInvalidMsgID
+	}
+
+	_, err := parseScope(d.Scope)
+
+	return err
+}
+
+// parseScope tells whether the deletion is for everyone, it's for the caller
+// only by default.
+func parseScope(scope string) (bool, error) {
+	switch scope {
+	case "", ScopeMe:
+		return false, nil
+	case ScopeEveryone:
+		return true, nil
+	default:
+		return false, ErrInvalidScope
+	}
+}
+
+func ParseMsgID(r *http.Request) (uint64, error) {
+	msgID, err := strconv.ParseUint(chi.URLParam(r, "msgID"), 10, 64)
+	if err != nil || msgID == 0 {
+		return 0, ErrInvalidMsgID
+	}
+
+	return msgID, nil
+}
+
+func ParseChatID(r *http.Request) (uint64, error) {
+	chatID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || chatID == 0 {
+		return 0, ErrInvalidChatID
+	}
+
+	return chatID, nil
+}
+
+// ParsePage reads ?before=, ?after= and ?limit= query parameters.
+func ParsePage(r *http.Request) (message.Page, error) {
+	var page message.Page
+
+	q := r.URL.Query()
+
+	if v := q.Get("before"); v != "" {
+		before, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return message.Page{}, ErrInvalidCursor
+		}
+		page.Before = before
+	}
+
+	if v := q.Get("after"); v != "" {
+		after, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return message.Page{}, ErrInvalidCursor
+		}
+		page.After = after
+	}
+
+	if page.Before != 0 && page.After != 0 {
+		return message.Page{}, ErrBothCursorsGiven
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return message.Page{}, ErrInvalidCursor
+		}
+		page.Limit = limit
+	}
+
+	return page, nil
+}
+
+type ErrorDTO struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+func NewErrorDTO(err error) ErrorDTO {
+	return ErrorDTO{
+		Message: err.Error(),
+		Time:    time.Now(),
+	}
+}
+
+func (e ErrorDTO) String() string {
+	b, _ := json.MarshalIndent(e, "", "    ")
+
+	return string(b)
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"messanger/internal/message"
+	"time"
+)
+
+type MessageResDTO struct {
+	ID           uint64           `json:"id"`
+	ChatID       uint64           `json:"chat_id"`
+	AuthorUserID uint64           `json:"author_user_id"`
+	Text         string           `json:"text"`
+	CreatedAt    time.Time        `json:"created_at"`
+	EditedAt     *time.Time       `json:"edited_at,omitempty"`
+	Deleted      bool             `json:"deleted,omitempty"`
+	ReplyToMsgID uint64           `json:"reply_to_msg_id,omitempty"`
+	ThreadRootID uint64           `json:"thread_root_id,omitempty"`
+	Service      string           `json:"service,omitempty"`
+	Thread       *ThreadResDTO    `json:"thread,omitempty"`
+	Reactions    []ReactionResDTO `json:"reactions,omitempty"`
+}
+
+func NewMessageResDTO(msg message.Message) MessageResDTO {
+	return MessageResDTO{
+		ID:           msg.ID,
+		ChatID:       msg.ChatID,
+		AuthorUserID: msg.AuthorUserID,
+		Text:         msg.Text,
+		CreatedAt:    msg.CreatedAt,
+		EditedAt:     msg.EditedAt,
+		Deleted:      msg.Deleted(),
+		ReplyToMsgID: msg.ReplyToMsgID,
+		ThreadRootID: msg.ThreadRootID,
+		Service:      msg.Service,
+		Thread:       NewThreadResDTO(msg.Thread),
+		Reactions:    NewReactionResDTOs(msg.Reactions),
+	}
+}
+
+type PinResDTO struct {
+	MsgID    uint64        `json:"msg_id"`
+	PinnedBy uint64        `json:"pinned_by,omitempty"`
+	PinnedAt time.Time     `json:"pinned_at"`
+	Message  MessageResDTO `json:"message"`
+}
+
+func NewPinResDTO(pin message.Pin) PinResDTO {
+	return PinResDTO{
+		MsgID:    pin.MsgID,
+		PinnedBy: pin.PinnedBy,
+		PinnedAt: pin.PinnedAt,
+		Message:  NewMessageResDTO(pin.Message),
+	}
+}
+
+type PinsResDTO struct {
+	Pins []PinResDTO `json:"pins"`
+}
+
+type ReactionResDTO struct {
+	Emoji   string `json:"emoji"`
+	Count   uint64 `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+type ReactionsResDTO struct {
+	Reactions []ReactionResDTO `json:"reactions"`
+}
+
+func NewReactionsResDTO(reactions []message.Reaction) ReactionsResDTO {
+	resp := ReactionsResDTO{Reactions: NewReactionResDTOs(reactions)}
+	if resp.Reactions == nil {
+		resp.Reactions = []ReactionResDTO{}
+	}
+
+	return resp
+}
+
+func NewReactionResDTOs(reactions []message.Reaction) []ReactionResDTO {
+	if len(reactions) == 0 {
+		return nil
+	}
+
+	dtos := make([]ReactionResDTO, 0, len(reactions))
+	for _, r := range reactions {
+		dtos = append(dtos, ReactionResDTO{
+			Emoji:   r.Emoji,
+			Count:   r.Count,
+			Reacted: r.Reacted,
+		})
+	}
+
+	return dtos
+}
+
+type ThreadResDTO struct {
+	ReplyCount     uint64     `json:"reply_count"`
+	LastReplyMsgID uint64     `json:"last_reply_msg_id,omitempty"`
+	LastReplyAt    *time.Time `json:"last_reply_at,omitempty"`
+	RecentRepliers []uint64   `json:"recent_repliers"`
+	LastReadMsgID  uint64     `json:"last_read_msg_id"`
+	UnreadCount    uint64     `json:"unread_count"`
+}
+
+func NewThreadResDTO(t *message.Thread) *ThreadResDTO {
+	if t == nil {
+		return nil
+	}
+
+	dto := &ThreadResDTO{
+		ReplyCount:     t.ReplyCount,
+		LastReplyMsgID: t.LastReplyMsgID,
+		RecentRepliers: t.RecentRepliers,
+		LastReadMsgID:  t.LastReadMsgID,
+		UnreadCount:    t.UnreadCount,
+	}
+	if dto.RecentRepliers == nil {
+		dto.RecentRepliers = []uint64{}
+	}
+	if t.ReplyCount > 0 {
+		dto.LastReplyAt = &t.LastReplyAt
+	}
+
+	return dto
+}
+
+type ThreadHistoryResDTO struct {
+	Root     MessageResDTO   `json:"root"`
+	Messages []MessageResDTO `json:"messages"`
+	HasMore  bool            `json:"has_more"`
+}
+
+type ThreadReadStateResDTO struct {
+	RootMsgID     uint64 `json:"root_msg_id"`
+	LastReadMsgID uint64 `json:"last_read_msg_id"`
+	UnreadCount   uint64 `json:"unread_count"`
+}
+
+func NewThreadReadStateResDTO(state message.ThreadReadState) ThreadReadStateResDTO {
+	return ThreadReadStateResDTO{
+		RootMsgID:     state.RootMsgID,
+		LastReadMsgID: state.LastReadMsgID,
+		UnreadCount:   state.UnreadCount,
+	}
+}
+
+type RevisionResDTO struct {
+	ID         uint64    `json:"id"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type RevisionsResDTO struct {
+	Revisions []RevisionResDTO `json:"revisions"`
+}
+
+type DeleteMessagesResDTO struct {
+	Deleted []uint64 `json:"deleted"`
+}
+
+type HistoryResDTO struct {
+	Messages []MessageResDTO `json:"messages"`
+	HasMore  bool            `json:"has_more"`
+}
+
+type ReadStateResDTO struct {
+	ChatID        uint64 `json:"chat_id"`
+	LastReadMsgID uint64 `json:"last_read_msg_id"`
+	UnreadCount   uint64 `json:"unread_count"`
+}
+
+func NewReadStateResDTO(state message.ReadState) ReadStateResDTO {
+	return ReadStateResDTO{
+		ChatID:        state.ChatID,
+		LastReadMsgID: state.LastReadMsgID,
+		UnreadCount:   state.UnreadCount,
+	}
+}
+
+type ReaderResDTO struct {
+	UserID        uint64 `json:"user_id"`
+	LastReadMsgID uint64 `json:"last_read_msg_id"`
+}
+
+type ReadByResDTO struct {
+	Readers []ReaderResDTO `json:"readers"`
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"net/http"
+)
+
+// Reply posts a message into the thread of the message in the path.
+func (h *MessageHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.Reply"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	rootID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var sendDTO SendMessageReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&sendDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := sendDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.msgUC.Reply(r.Context(), uid, chatID, rootID, sendDTO.Text, sendDTO.ReplyToMsgID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(NewMessageResDTO(msg))
+}
+
+// Thread returns the root message with its thread summary and a page of
+// replies, paged like History.
+func (h *MessageHandler) Thread(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	rootID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := ParsePage(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	root, msgs, hasMore, err := h.msgUC.Thread(r.Context(), uid, chatID, rootID, page)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	resp := ThreadHistoryResDTO{
+		Root:     NewMessageResDTO(root),
+		Messages: make([]MessageResDTO, 0, len(msgs)),
+		HasMore:  hasMore,
+	}
+	for _, msg := range msgs {
+		resp.Messages = append(resp.Messages, NewMessageResDTO(msg))
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *MessageHandler) MarkThreadRead(w http.ResponseWriter, r *http.Request) {
+	const op = "message.http.handler.MarkThreadRead"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	uid, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID, err := ParseChatID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	rootID, err := ParseMsgID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	var markReadDTO MarkReadReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&markReadDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := markReadDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.msgUC.MarkThreadRead(r.Context(), uid, chatID, rootID, markReadDTO.MsgID)
+	if err != nil {
+		h.writeUCError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewThreadReadStateResDTO(state))
+}
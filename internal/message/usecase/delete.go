@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message"
+	"messanger/internal/message/repository"
+	"slices"
+	"time"
+)
+
+var (
+	ErrMessageDeleted      = errors.New("the message is deleted")
+	ErrDeleteWindowExpired = errors.New("the message can no longer be deleted for everyone")
+)
+
+// Delete deletes the messages of the chat and returns their IDs. Deleted for
+// the caller only, they are hidden from the caller's history. Deleted for
+// everyone, they become tombstones: the ID stays, the text and revisions
+// are gone. Either all the messages are deleted or none is.
+func (m *Message) Delete(ctx context.Context, userID, chatID uint64, ids []uint64, forEveryone bool) ([]uint64, error) {
+	const op = "message.usecase.delete.Delete"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Int("count", len(ids)),
+		slog.Bool("for_everyone", forEveryone),
+	)
+
+	role, err := m.authorize(ctx, userID, chatID, "")
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	msgs, err := m.msgRepo.GetMany(ctx, chatID, ids)
+	if err != nil {
+		log.Error("failed to get messages", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(msgs) != len(ids) {
+		return nil, fmt.Errorf("%s: %w", op, repository.ErrMessageNotFound)
+	}
+
+	if !forEveryone {
+		err := m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+			if err := m.msgRepo.Hide(ctx, userID, ids); err != nil {
+				return err
+			}
+
+			return m.publish(ctx, event.MessageHidden, chatID, userID, event.DeletedPayload{IDs: ids}, []uint64{userID})
+		})
+		if err != nil {
+			log.Error("failed to hide messages", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return ids, nil
+	}
+
+	typ, err := m.msgRepo.GetChatType(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Tombstones are already deleted for everyone, deleting them again is a
+	// no-op rather than an error.
+	toDelete := make([]uint64, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Deleted() {
+			continue
+		}
+
+		if err := m.canDeleteForEveryone(userID, role, typ, msg); err != nil {
+			log.Warn("access denied", slog.Uint64("msg_id", msg.ID), sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		toDelete = append(toDelete, msg.ID)
+	}
+
+	if len(toDelete) == 0 {
+		return ids, nil
+	}
+
+	err = m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		unpinned, err := m.msgRepo.Tombstone(ctx, toDelete, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := m.publish(ctx, event.MessageDeleted, chatID, userID, event.DeletedPayload{IDs: toDelete}, nil); err != nil {
+			return err
+		}
+
+		for _, msgID := range unpinned {
+			err := m.publish(ctx, event.MessageUnpinned, chatID, userID, event.PinPayload{
+				MsgID: msgID,
+			}, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to delete messages", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// canDeleteForEveryone lets the author delete within deleteWindow, if their
+// role still allows writing such a message, and the roles that may delete
+// others' messages at any time.
+func (m *Message) canDeleteForEveryone(userID uint64, role chat.Role, chatType string, msg message.Message) error {
+	if role.Can(chat.ActionDeleteOthersMessages) {
+		return nil
+	}
+
+	if msg.AuthorUserID != userID || !role.Can(writeAction(chatType, msg.ThreadRootID != 0)) {
+		return ErrForbidden
+	}
+
+	if m.deleteWindow > 0 && time.Since(msg.CreatedAt) > m.deleteWindow {
+		return ErrDeleteWindowExpired
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message"
+	"messanger/internal/message/repository"
+	"time"
+)
+
+var (
+	ErrNotAuthor         = errors.New("only the author can edit the message")
+	ErrEditWindowExpired = errors.New("the message can no longer be edited")
+)
+
+// Edit replaces the text of the caller's own message, the previous text is
+// kept as a revision. Members are notified with a message.edited event.
+func (m *Message) Edit(ctx context.Context, userID, chatID, msgID uint64, text string) (message.Message, error) {
+	const op = "message.usecase.edit.Edit"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	role, err := m.authorize(ctx, userID, chatID, "")
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg, err := m.getInChat(ctx, chatID, msgID)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if msg.AuthorUserID != userID {
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrNotAuthor)
+	}
+
+	typ, err := m.msgRepo.GetChatType(ctx, chatID)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !role.Can(writeAction(typ, msg.ThreadRootID != 0)) {
+		log.Warn("access denied", sl.Err(ErrForbidden))
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	if msg.Deleted() {
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrMessageDeleted)
+	}
+
+	if msg.Service != "" {
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrServiceMessage)
+	}
+
+	if m.editWindow > 0 && time.Since(msg.CreatedAt) > m.editWindow {
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrEditWindowExpired)
+	}
+
+	if msg.Text == text {
+		return msg, nil
+	}
+
+	err = m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		msg, err = m.msgRepo.Edit(ctx, msgID, text)
+		if err != nil {
+			return err
+		}
+
+		return m.publish(ctx, event.MessageEdited, msg.ChatID, msg.AuthorUserID, messagePayload(msg), nil)
+	})
+	if err != nil {
+		// Deleted for everyone after it was read above.
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return message.Message{}, fmt.Errorf("%s: %w", op, ErrMessageDeleted)
+		}
+
+		log.Error("message edit error", sl.Err(err))
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msg, nil
+}
+
+// Revisions returns the versions of a message replaced by edits, to its
+// author and to the members allowed to view them.
+func (m *Message) Revisions(ctx context.Context, userID, chatID, msgID uint64) ([]message.Revision, error) {
+	const op = "message.usecase.edit.Revisions"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	role, err := m.authorize(ctx, userID, chatID, "")
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg, err := m.getInChat(ctx, chatID, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if msg.AuthorUserID != userID && !role.Can(chat.ActionViewRevisions) {
+		return nil, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	revisions, err := m.msgRepo.ListRevisions(ctx, msgID)
+	if err != nil {
+		log.Error("failed to list revisions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revisions, nil
+}
@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message"
+	"messanger/internal/message/repository"
+	"time"
+)
+
+var (
+	ErrNotChatMember = errors.New("user is not a member of the chat")
+	ErrForbidden     = errors.New("action is not allowed for the role")
+)
+
+type MessageUC interface {
+	Send(ctx context.Context, userID, chatID uint64, text string, replyToMsgID uint64) (message.Message, error)
+	History(ctx context.Context, userID, chatID uint64, page message.Page) ([]message.Message, bool, error)
+
+	Reply(ctx context.Context, userID, chatID, rootID uint64, text string, replyToMsgID uint64) (message.Message, error)
+	Thread(ctx context.Context, userID, chatID, rootID uint64, page message.Page) (message.Message, []message.Message, bool, error)
+	MarkThreadRead(ctx context.Context, userID, chatID, rootID, msgID uint64) (message.ThreadReadState, error)
+
+	React(ctx context.Context, userID, chatID, msgID uint64, emoji string) ([]message.Reaction, error)
+	Unreact(ctx context.Context, userID, chatID, msgID uint64, emoji string) ([]message.Reaction, error)
+
+	Pin(ctx context.Context, userID, chatID, msgID uint64) (message.Pin, error)
+	Unpin(ctx context.Context, userID, chatID, msgID uint64) error
+	Pins(ctx context.Context, userID, chatID uint64) ([]message.Pin, error)
+
+	Edit(ctx context.Context, userID, chatID, msgID uint64, text string) (message.Message, error)
+	Revisions(ctx context.Context, userID, chatID, msgID uint64) ([]message.Revision, error)
+	Delete(ctx context.Context, userID, chatID uint64, ids []uint64, forEveryone bool) ([]uint64, error)
+
+	MarkRead(ctx context.Context, userID, chatID, msgID uint64) (message.ReadState, error)
+	ReadState(ctx context.Context, userID, chatID uint64) (message.ReadState, error)
+	ReadBy(ctx context.Context, userID, chatID, msgID uint64) ([]message.Reader, error)
+}
+
+type Message struct {
+	log          *slog.Logger
+	msgRepo      repository.MessageRepo
+	publisher    event.Publisher
+	editWindow   time.Duration
+	deleteWindow time.Duration
+}
+
+// NewMessage makes the message usecase. Authors can edit their messages for
+// editWindow and delete them for everyone for deleteWindow after they are
+// sent, zero means there's no limit.
+func NewMessage(log *slog.Logger, msgRepo repository.MessageRepo, publisher event.Publisher, editWindow, deleteWindow time.Duration) *Message {
+	return &Message{
+		log:          log,
+		msgRepo:      msgRepo,
+		publisher:    publisher,
+		editWindow:   editWindow,
+		deleteWindow: deleteWindow,
+	}
+}
+
+// Send posts a message into the chat history, quoting replyToMsgID unless
+// it's zero.
+func (m *Message) Send(ctx context.Context, userID, chatID uint64, text string, replyToMsgID uint64) (message.Message, error) {
+	const op = "message.usecase.message.Send"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, chat.ActionPost); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.checkReplyTo(ctx, chatID, 0, replyToMsgID); err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	draft := message.NewMessage(chatID, userID, text)
+	draft.ReplyToMsgID = replyToMsgID
+
+	msg, err := m.create(ctx, draft)
+	if err != nil {
+		log.Error("message creation error", sl.Err(err))
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The author has obviously read everything up to their own message.
+	if _, err := m.msgRepo.MarkRead(ctx, userID, chatID, msg.ID); err != nil {
+		log.Warn("failed to move read marker", sl.Err(err))
+	}
+
+	return msg, nil
+}
+
+// History returns a page of chat history and reports whether there are more
+// messages in the requested direction. Messages come with their reactions
+// and, if they have replies, their thread summaries.
+func (m *Message) History(ctx context.Context, userID, chatID uint64, page message.Page) ([]message.Message, bool, error) {
+	const op = "message.usecase.message.History"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page = page.Normalize()
+	limit := page.Limit
+	page.Limit++
+
+	msgs, err := m.msgRepo.List(ctx, chatID, userID, page)
+	if err != nil {
+		log.Error("failed to list messages", sl.Err(err))
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hasMore := len(msgs) > limit
+	if hasMore {
+		msgs = msgs[:limit]
+	}
+
+	if err := m.attachThreads(ctx, userID, msgs); err != nil {
+		log.Error("failed to get threads", sl.Err(err))
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.attachReactions(ctx, userID, msgs); err != nil {
+		log.Error("failed to get reactions", sl.Err(err))
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msgs, hasMore, nil
+}
+
+// authorize returns the user's role in the chat if it permits the action.
+// An empty action only requires membership.
+func (m *Message) authorize(ctx context.Context, userID, chatID uint64, action chat.Action) (chat.Role, error) {
+	role, err := m.msgRepo.GetMemberRole(ctx, userID, chatID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			return "", ErrNotChatMember
+		}
+
+		return "", err
+	}
+
+	if action != "" && !role.Can(action) {
+		return "", ErrForbidden
+	}
+
+	return role, nil
+}
+
+func messagePayload(msg message.Message) event.MessagePayload {
+	return event.MessagePayload{
+		ID:           msg.ID,
+		ChatID:       msg.ChatID,
+		AuthorUserID: msg.AuthorUserID,
+		Text:         msg.Text,
+		CreatedAt:    msg.CreatedAt,
+		EditedAt:     msg.EditedAt,
+		ReplyToMsgID: msg.ReplyToMsgID,
+		ThreadRootID: msg.ThreadRootID,
+		Service:      msg.Service,
+	}
+}
+
+// create stores the message and journals it in one transaction.
+func (m *Message) create(ctx context.Context, draft message.Message) (message.Message, error) {
+	var msg message.Message
+
+	err := m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		msg, err = m.msgRepo.Create(ctx, draft)
+		if err != nil {
+			return err
+		}
+
+		return m.publish(ctx, event.MessageCreated, msg.ChatID, msg.AuthorUserID, messagePayload(msg), nil)
+	})
+	if err != nil {
+		return message.Message{}, err
+	}
+
+	return msg, nil
+}
+
+// publish journals the event in the transaction of ctx, so a failure rolls
+// the change back. Subscribers get it once the transaction commits.
+func (m *Message) publish(ctx context.Context, typ event.Type, chatID, userID uint64, payload any, recipients []uint64) error {
+	ev, err := event.New(typ, chatID, userID, payload)
+	if err != nil {
+		return err
+	}
+	ev.Recipients = recipients
+
+	return m.publisher.Publish(ctx, ev)
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message"
+)
+
+var (
+	ErrServiceMessage = errors.New("service messages can't be changed, pinned, reacted or replied to")
+)
+
+// Pin pins a message of the chat history. The pin is announced with a
+// service message in the history and a message.pinned event.
+func (m *Message) Pin(ctx context.Context, userID, chatID, msgID uint64) (message.Pin, error) {
+	const op = "message.usecase.pin.Pin"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, chat.ActionPin); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.Pin{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg, err := m.getInChat(ctx, chatID, msgID)
+	if err != nil {
+		return message.Pin{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case msg.Deleted():
+		return message.Pin{}, fmt.Errorf("%s: %w", op, ErrMessageDeleted)
+	case msg.ThreadRootID != 0:
+		return message.Pin{}, fmt.Errorf("%s: %w", op, ErrInThread)
+	case msg.Service != "":
+		return message.Pin{}, fmt.Errorf("%s: %w", op, ErrServiceMessage)
+	}
+
+	var pin message.Pin
+
+	err = m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		var (
+			service message.Message
+			err     error
+		)
+
+		pin, service, err = m.msgRepo.Pin(ctx, message.Pin{
+			ChatID:   chatID,
+			MsgID:    msgID,
+			PinnedBy: userID,
+		}, message.MaxPins)
+		if err != nil {
+			return err
+		}
+
+		if err := m.publish(ctx, event.MessageCreated, chatID, userID, messagePayload(service), nil); err != nil {
+			return err
+		}
+
+		return m.publish(ctx, event.MessagePinned, chatID, userID, event.PinPayload{
+			MsgID:    msgID,
+			PinnedBy: userID,
+			PinnedAt: &pin.PinnedAt,
+		}, nil)
+	})
+	if err != nil {
+		log.Warn("failed to pin message", sl.Err(err))
+		return message.Pin{}, fmt.Errorf("%s: %w", op, err)
+	}
+	pin.Message = msg
+
+	return pin, nil
+}
+
+// Unpin unpins a message, announced like Pin.
+func (m *Message) Unpin(ctx context.Context, userID, chatID, msgID uint64) error {
+	const op = "message.usecase.pin.Unpin"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, chat.ActionPin); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		service, err := m.msgRepo.Unpin(ctx, chatID, msgID, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := m.publish(ctx, event.MessageCreated, chatID, userID, messagePayload(service), nil); err != nil {
+			return err
+		}
+
+		return m.publish(ctx, event.MessageUnpinned, chatID, userID, event.PinPayload{
+			MsgID: msgID,
+		}, nil)
+	})
+	if err != nil {
+		log.Warn("failed to unpin message", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Pins lists the pinned messages of the chat, the latest pin first.
+func (m *Message) Pins(ctx context.Context, userID, chatID uint64) ([]message.Pin, error) {
+	const op = "message.usecase.pin.Pins"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pins, err := m.msgRepo.ListPins(ctx, chatID)
+	if err != nil {
+		log.Error("failed to list pins", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pins, nil
+}
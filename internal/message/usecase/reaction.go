@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message"
+	"slices"
+)
+
+var (
+	ErrReactionNotAllowed = errors.New("the reaction is not allowed in the chat")
+)
+
+// React adds the caller's reaction to a message and returns the resulting
+// reactions of the message. Reacting again with the same emoji is a no-op.
+func (m *Message) React(ctx context.Context, userID, chatID, msgID uint64, emoji string) ([]message.Reaction, error) {
+	const op = "message.usecase.reaction.React"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg, err := m.getInChat(ctx, chatID, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case msg.Deleted():
+		return nil, fmt.Errorf("%s: %w", op, ErrMessageDeleted)
+	case msg.Service != "":
+		return nil, fmt.Errorf("%s: %w", op, ErrServiceMessage)
+	}
+
+	allowed, err := m.msgRepo.GetAllowedReactions(ctx, chatID)
+	if err != nil {
+		log.Error("failed to get allowed reactions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if allowed != nil && !slices.Contains(allowed, emoji) {
+		return nil, fmt.Errorf("%s: %w", op, ErrReactionNotAllowed)
+	}
+
+	err = m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		added, err := m.msgRepo.AddReaction(ctx, msgID, userID, emoji, message.MaxReactionKinds)
+		if err != nil || !added {
+			return err
+		}
+
+		return m.publish(ctx, event.ReactionAdded, chatID, userID, event.ReactionPayload{
+			MsgID: msgID,
+			Emoji: emoji,
+		}, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reactions, err := m.msgRepo.GetReactions(ctx, userID, []uint64{msgID})
+	if err != nil {
+		log.Error("failed to get reactions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reactions, nil
+}
+
+// Unreact removes the caller's reaction and returns the resulting reactions
+// of the message. Removing a missing reaction is a no-op.
+func (m *Message) Unreact(ctx context.Context, userID, chatID, msgID uint64, emoji string) ([]message.Reaction, error) {
+	const op = "message.usecase.reaction.Unreact"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := m.getInChat(ctx, chatID, msgID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		removed, err := m.msgRepo.RemoveReaction(ctx, msgID, userID, emoji)
+		if err != nil || !removed {
+			return err
+		}
+
+		return m.publish(ctx, event.ReactionRemoved, chatID, userID, event.ReactionPayload{
+			MsgID: msgID,
+			Emoji: emoji,
+		}, nil)
+	})
+	if err != nil {
+		log.Error("failed to remove reaction", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reactions, err := m.msgRepo.GetReactions(ctx, userID, []uint64{msgID})
+	if err != nil {
+		log.Error("failed to get reactions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reactions, nil
+}
+
+// attachReactions sets the reactions of the messages.
+func (m *Message) attachReactions(ctx context.Context, userID uint64, msgs []message.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+
+	reactions, err := m.msgRepo.GetReactions(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	byMsg := make(map[uint64][]message.Reaction, len(msgs))
+	for _, r := range reactions {
+		byMsg[r.MsgID] = append(byMsg[r.MsgID], r)
+	}
+
+	for i := range msgs {
+		msgs[i].Reactions = byMsg[msgs[i].ID]
+	}
+
+	return nil
+}
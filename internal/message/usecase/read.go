@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message"
+	"messanger/internal/message/repository"
+)
+
+var (
+	ErrReadByUnavailable = errors.New("read receipts are not available in channels")
+)
+
+// MarkRead moves the caller's read marker up to msgID. Moving it backwards is
+// a no-op, the current state is returned either way.
+func (m *Message) MarkRead(ctx context.Context, userID, chatID, msgID uint64) (message.ReadState, error) {
+	const op = "message.usecase.read.MarkRead"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg, err := m.getInChat(ctx, chatID, msgID)
+	if err != nil {
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Thread replies are read with MarkThreadRead, their IDs would move the
+	// chat marker past unread history.
+	if msg.ThreadRootID != 0 {
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, ErrInThread)
+	}
+
+	before, err := m.msgRepo.GetReadState(ctx, userID, chatID)
+	if err != nil {
+		log.Error("failed to get read state", sl.Err(err))
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if msgID <= before.LastReadMsgID {
+		return before, nil
+	}
+
+	// Other members use read markers for double checks. In channels nobody
+	// but the reader's own devices needs to know.
+	var recipients []uint64
+
+	typ, err := m.msgRepo.GetChatType(ctx, chatID)
+	if err != nil {
+		log.Error("failed to get chat type", sl.Err(err))
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if typ == chat.TypeChannel {
+		recipients = []uint64{userID}
+	}
+
+	var state message.ReadState
+
+	err = m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		if _, err := m.msgRepo.MarkRead(ctx, userID, chatID, msgID); err != nil {
+			return err
+		}
+
+		var err error
+
+		state, err = m.msgRepo.GetReadState(ctx, userID, chatID)
+		if err != nil {
+			return err
+		}
+
+		return m.publish(ctx, event.ReadMarkerMoved, chatID, userID, event.ReadPayload{
+			LastReadMsgID: state.LastReadMsgID,
+		}, recipients)
+	})
+	if err != nil {
+		log.Error("failed to move read marker", sl.Err(err))
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return state, nil
+}
+
+func (m *Message) ReadState(ctx context.Context, userID, chatID uint64) (message.ReadState, error) {
+	const op = "message.usecase.read.ReadState"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	state, err := m.msgRepo.GetReadState(ctx, userID, chatID)
+	if err != nil {
+		log.Error("failed to get read state", sl.Err(err))
+		return message.ReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return state, nil
+}
+
+// ReadBy lists the members who have read the message, except its author.
+func (m *Message) ReadBy(ctx context.Context, userID, chatID, msgID uint64) ([]message.Reader, error) {
+	const op = "message.usecase.read.ReadBy"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	typ, err := m.msgRepo.GetChatType(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if typ == chat.TypeChannel {
+		return nil, fmt.Errorf("%s: %w", op, ErrReadByUnavailable)
+	}
+
+	msg, err := m.getInChat(ctx, chatID, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if msg.ThreadRootID != 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInThread)
+	}
+
+	readers, err := m.msgRepo.ListReaders(ctx, chatID, msgID, msg.AuthorUserID)
+	if err != nil {
+		log.Error("failed to list readers", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return readers, nil
+}
+
+// getInChat returns the message if it belongs to the chat.
+func (m *Message) getInChat(ctx context.Context, chatID, msgID uint64) (message.Message, error) {
+	msg, err := m.msgRepo.GetByID(ctx, msgID)
+	if err != nil {
+		return message.Message{}, err
+	}
+
+	if msg.ChatID != chatID {
+		return message.Message{}, repository.ErrMessageNotFound
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,321 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/chat"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/message"
+)
+
+var (
+	ErrThreadsUnavailable = errors.New("threads are not available in private chats")
+	ErrNotThreadRoot      = errors.New("only top-level messages have threads")
+	ErrInThread           = errors.New("the message is in a thread")
+	ErrNotInThread        = errors.New("the message is not in the thread")
+)
+
+// Reply posts a message into the thread of rootID. In channels the thread
+// holds the comments to a post, so subscribers may reply there although they
+// can't post into the channel.
+func (m *Message) Reply(ctx context.Context, userID, chatID, rootID uint64, text string, replyToMsgID uint64) (message.Message, error) {
+	const op = "message.usecase.thread.Reply"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("root_msg_id", rootID),
+	)
+
+	role, err := m.authorize(ctx, userID, chatID, "")
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	root, typ, err := m.threadRoot(ctx, chatID, rootID)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !role.Can(writeAction(typ, true)) {
+		log.Warn("access denied", sl.Err(ErrForbidden))
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrForbidden)
+	}
+
+	switch {
+	case root.Deleted():
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrMessageDeleted)
+	case root.Service != "":
+		return message.Message{}, fmt.Errorf("%s: %w", op, ErrServiceMessage)
+	}
+
+	if err := m.checkReplyTo(ctx, chatID, rootID, replyToMsgID); err != nil {
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	draft := message.NewMessage(chatID, userID, text)
+	draft.ThreadRootID = rootID
+	draft.ReplyToMsgID = replyToMsgID
+
+	msg, err := m.create(ctx, draft)
+	if err != nil {
+		log.Error("message creation error", sl.Err(err))
+		return message.Message{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := m.msgRepo.MarkThreadRead(ctx, userID, rootID, msg.ID); err != nil {
+		log.Warn("failed to move thread read marker", sl.Err(err))
+	}
+
+	return msg, nil
+}
+
+// Thread returns the root message with its thread summary and a page of its
+// replies, reporting whether there are more in the requested direction. The
+// root and the replies come with their reactions.
+func (m *Message) Thread(ctx context.Context, userID, chatID, rootID uint64, page message.Page) (message.Message, []message.Message, bool, error) {
+	const op = "message.usecase.thread.Thread"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("root_msg_id", rootID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.Message{}, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	root, _, err := m.threadRoot(ctx, chatID, rootID)
+	if err != nil {
+		return message.Message{}, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	thread, err := m.thread(ctx, userID, rootID)
+	if err != nil {
+		log.Error("failed to get thread", sl.Err(err))
+		return message.Message{}, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	root.Thread = &thread
+
+	page = page.Normalize()
+	limit := page.Limit
+	page.Limit++
+
+	msgs, err := m.msgRepo.ListThread(ctx, rootID, userID, page)
+	if err != nil {
+		log.Error("failed to list replies", sl.Err(err))
+		return message.Message{}, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hasMore := len(msgs) > limit
+	if hasMore {
+		msgs = msgs[:limit]
+	}
+
+	all := append([]message.Message{root}, msgs...)
+	if err := m.attachReactions(ctx, userID, all); err != nil {
+		log.Error("failed to get reactions", sl.Err(err))
+		return message.Message{}, nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return all[0], all[1:], hasMore, nil
+}
+
+// MarkThreadRead moves the caller's read marker in the thread up to msgID,
+// like MarkRead does in the chat history.
+func (m *Message) MarkThreadRead(ctx context.Context, userID, chatID, rootID, msgID uint64) (message.ThreadReadState, error) {
+	const op = "message.usecase.thread.MarkThreadRead"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("chat_id", chatID),
+		slog.Uint64("root_msg_id", rootID),
+		slog.Uint64("msg_id", msgID),
+	)
+
+	if _, err := m.authorize(ctx, userID, chatID, ""); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return message.ThreadReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, _, err := m.threadRoot(ctx, chatID, rootID); err != nil {
+		return message.ThreadReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg, err := m.getInChat(ctx, chatID, msgID)
+	if err != nil {
+		return message.ThreadReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if msg.ThreadRootID != rootID {
+		return message.ThreadReadState{}, fmt.Errorf("%s: %w", op, ErrNotInThread)
+	}
+
+	before, err := m.thread(ctx, userID, rootID)
+	if err != nil {
+		log.Error("failed to get thread", sl.Err(err))
+		return message.ThreadReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if msgID <= before.LastReadMsgID {
+		return threadReadState(before), nil
+	}
+
+	var after message.Thread
+
+	err = m.msgRepo.InTx(ctx, func(ctx context.Context) error {
+		if _, err := m.msgRepo.MarkThreadRead(ctx, userID, rootID, msgID); err != nil {
+			return err
+		}
+
+		var err error
+
+		after, err = m.thread(ctx, userID, rootID)
+		if err != nil {
+			return err
+		}
+
+		// Thread markers only sync the reader's own devices.
+		return m.publish(ctx, event.ThreadRead, chatID, userID, event.ThreadReadPayload{
+			RootMsgID:     rootID,
+			LastReadMsgID: after.LastReadMsgID,
+		}, []uint64{userID})
+	})
+	if err != nil {
+		log.Error("failed to move thread read marker", sl.Err(err))
+		return message.ThreadReadState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return threadReadState(after), nil
+}
+
+// writeAction is the action it takes to write, edit or delete one's own
+// message: channel subscribers may comment in threads but not post.
+func writeAction(chatType string, inThread bool) chat.Action {
+	if inThread && chatType == chat.TypeChannel {
+		return chat.ActionComment
+	}
+
+	return chat.ActionPost
+}
+
+// threadRoot returns the message if it can have a thread in the chat, along
+// with the chat type.
+func (m *Message) threadRoot(ctx context.Context, chatID, rootID uint64) (message.Message, string, error) {
+	typ, err := m.msgRepo.GetChatType(ctx, chatID)
+	if err != nil {
+		return message.Message{}, "", err
+	}
+
+	if typ == chat.TypePrivate {
+		return message.Message{}, "", ErrThreadsUnavailable
+	}
+
+	root, err := m.getInChat(ctx, chatID, rootID)
+	if err != nil {
+		return message.Message{}, "", err
+	}
+
+	if root.ThreadRootID != 0 {
+		return message.Message{}, "", ErrNotThreadRoot
+	}
+
+	return root, typ, nil
+}
+
+// checkReplyTo makes sure a quoted message is a live user message in the same
+// conversation: the chat history for threadRootID zero, the thread or its root
+// otherwise.
+func (m *Message) checkReplyTo(ctx context.Context, chatID, threadRootID, replyToMsgID uint64) error {
+	if replyToMsgID == 0 {
+		return nil
+	}
+
+	target, err := m.getInChat(ctx, chatID, replyToMsgID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case target.Deleted():
+		return ErrMessageDeleted
+	case target.Service != "":
+		return ErrServiceMessage
+	}
+
+	if threadRootID == 0 {
+		if target.ThreadRootID != 0 {
+			return ErrInThread
+		}
+
+		return nil
+	}
+
+	if target.ID != threadRootID && target.ThreadRootID != threadRootID {
+		return ErrNotInThread
+	}
+
+	return nil
+}
+
+// thread returns the summary of the thread, an empty one if it has no
+// replies yet.
+func (m *Message) thread(ctx context.Context, userID, rootID uint64) (message.Thread, error) {
+	threads, err := m.msgRepo.GetThreads(ctx, userID, []uint64{rootID})
+	if err != nil {
+		return message.Thread{}, err
+	}
+
+	if len(threads) == 0 {
+		return message.Thread{RootMsgID: rootID}, nil
+	}
+
+	return threads[0], nil
+}
+
+// attachThreads sets the summaries of the threads the messages have.
+func (m *Message) attachThreads(ctx context.Context, userID uint64, msgs []message.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+
+	threads, err := m.msgRepo.GetThreads(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	byRoot := make(map[uint64]message.Thread, len(threads))
+	for _, t := range threads {
+		byRoot[t.RootMsgID] = t
+	}
+
+	for i := range msgs {
+		if t, ok := byRoot[msgs[i].ID]; ok {
+			msgs[i].Thread = &t
+		}
+	}
+
+	return nil
+}
+
+func threadReadState(t message.Thread) message.ThreadReadState {
+	return message.ThreadReadState{
+		RootMsgID:     t.RootMsgID,
+		LastReadMsgID: t.LastReadMsgID,
+		UnreadCount:   t.UnreadCount,
+	}
+}
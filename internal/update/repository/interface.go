@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"messanger/internal/event"
+	"messanger/internal/update"
+	"time"
+)
+
+type UpdateRepo interface {
+	UpdateReader
+	UpdateWriter
+}
+
+type UpdateReader interface {
+	// ListSince returns the user's updates after the cursor, in log order.
+	ListSince(ctx context.Context, userID uint64, since update.Cursor, limit int) ([]update.Update, error)
+	// Horizon is the position that nothing that shows up later can precede.
+	Horizon(ctx context.Context) (update.Cursor, error)
+}
+
+type UpdateWriter interface {
+	// Append stores the event and returns the position of its last row. It
+	// joins the transaction of ctx, if there is one.
+	Append(ctx context.Context, ev event.Event) (update.Cursor, error)
+	DeleteOlderThan(ctx context.Context, t time.Time) (int64, error)
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"messanger/internal/event"
+	"messanger/internal/lib/pgtx"
+	"messanger/internal/update"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// memberTypes are the events about a user's membership, which reach the user
+// whether or not they are a member.
+var memberTypes = []string{
+	string(event.MemberJoined),
+	string(event.MemberLeft),
+	string(event.MemberRole),
+	string(event.MemberBanned),
+	string(event.MemberUnbanned),
+}
+
+type Storage struct {
+	db *pgxpool.Pool
+}
+
+func New(ctx context.Context, dbURL string) (*Storage, error) {
+	const op = "update.repository.postgres.New"
+
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: pool}, nil
+}
+
+func (s *Storage) Close(ctx context.Context) error {
+	s.db.Close()
+
+	return nil
+}
+
+// InTx runs fn in a transaction. Repositories called with the context fn
+// gets write in that transaction.
+func (s *Storage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return pgtx.Run(ctx, s.db, fn)
+}
+
+// conn is the transaction of ctx if there is one, or the pool.
+func (s *Storage) conn(ctx context.Context) pgtx.Conn {
+	return pgtx.From(ctx, s.db)
+}
+
+// Append writes one row visible to the chat members, or one row per
+// recipient when the event is addressed to specific users. Membership
+// events also open or close the user's period of access to the chat's
+// updates, so readers see the chat as it was when each event happened.
+func (s *Storage) Append(ctx context.Context, ev event.Event) (update.Cursor, error) {
+	const op = "update.repository.postgres.Append"
+
+	var cursor update.Cursor
+
+	err := s.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		cursor, err = s.append(ctx, ev)
+		return err
+	})
+	if err != nil {
+		return update.Cursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cursor, nil
+}
+
+func (s *Storage) append(ctx context.Context, ev event.Event) (update.Cursor, error) {
+	args := pgx.NamedArgs{
+		"chat_id":    ev.ChatID,
+		"user_id":    nullableID(ev.UserID),
+		"type":       string(ev.Type),
+		"payload":    ev.Payload,
+		"created_at": ev.Time,
+	}
+
+	var sql string
+
+	if len(ev.Recipients) == 0 {
+		sql = `INSERT INTO updates(chat_id, user_id, type, payload, created_at)
+			VALUES(@chat_id, @user_id, @type, @payload, @created_at)
+			RETURNING tx_id::text::bigint, id`
+	} else {
+		sql = `INSERT INTO updates(chat_id, user_id, recipient_user_id, type, payload, created_at)
+			SELECT @chat_id, @user_id, r, @type, @payload, @created_at FROM unnest(@recipients::BIGINT[]) AS r
+			RETURNING tx_id::text::bigint, id`
+		args["recipients"] = ev.Recipients
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return update.Cursor{}, err
+	}
+
+	cursors, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (update.Cursor, error) {
+		c := update.Cursor{Time: ev.Time}
+		err := row.Scan(&c.TxID, &c.ID)
+		return c, err
+	})
+	if err != nil {
+		return update.Cursor{}, err
+	}
+
+	cursor := cursors[len(cursors)-1]
+
+	if err := s.trackMembership(ctx, ev, cursor.ID); err != nil {
+		return update.Cursor{}, err
+	}
+
+	return cursor, nil
+}
+
+// trackMembership opens a period of access to the chat's updates on join
+// and closes it on leave, kick, ban or deletion of the chat. The closing
+// update is still in the period, so users learn that they were removed.
+func (s *Storage) trackMembership(ctx context.Context, ev event.Event, id uint64) error {
+	var sql string
+
+	switch ev.Type {
+	case event.MemberJoined:
+		sql = `INSERT INTO update_memberships(chat_id, user_id, from_id) VALUES(@chat_id, @user_id, @id)
+			ON CONFLICT (chat_id, user_id) WHERE until_id IS NULL DO NOTHING`
+	case event.MemberLeft, event.MemberBanned:
+		sql = `UPDATE update_memberships SET until_id = @id
+			WHERE chat_id = @chat_id AND user_id = @user_id AND until_id IS NULL`
+	case event.ChatDeleted:
+		sql = `UPDATE update_memberships SET until_id = @id
+			WHERE chat_id = @chat_id AND until_id IS NULL`
+	default:
+		return nil
+	}
+
+	args := pgx.NamedArgs{
+		"chat_id": ev.ChatID,
+		"user_id": ev.UserID,
+		"id":      id,
+	}
+
+	_, err := s.conn(ctx).Exec(ctx, sql, args)
+	return err
+}
+
+// ListSince returns updates after the cursor that are addressed to the user,
+// are about the user's membership or belong to a chat the user was a member
+// of at the time.
+//
+// IDs are taken before commit, so an update can become visible after ones
+// with higher IDs. Only updates of transactions older than every running
+// one are returned, in the order of their transaction: nothing can show up
+// before them later.
+func (s *Storage) ListSince(ctx context.Context, userID uint64, since update.Cursor, limit int) ([]update.Update, error) {
+	const op = "update.repository.postgres.ListSince"
+
+	sql := `SELECT u.id, u.tx_id::text::bigint, u.chat_id, u.user_id, u.recipient_user_id, u.type, u.payload, u.created_at
+		FROM updates u
+		WHERE (u.tx_id, u.id) > (@since_tx::bigint::text::xid8, @since_id)
+			AND u.tx_id < pg_snapshot_xmin(pg_current_snapshot())
+			AND (
+				u.recipient_user_id = @user_id
+				OR (u.recipient_user_id IS NULL AND (
+					(u.user_id = @user_id AND u.type = ANY(@member_types))
+					OR EXISTS (SELECT 1 FROM update_memberships m
+						WHERE m.chat_id = u.chat_id AND m.user_id = @user_id
+							AND u.id >= m.from_id AND (m.until_id IS NULL OR u.id <= m.until_id))
+				))
+			)
+		ORDER BY u.tx_id, u.id LIMIT @limit`
+	args := pgx.NamedArgs{
+		"since_tx":     since.TxID,
+		"since_id":     since.ID,
+		"user_id":      userID,
+		"member_types": memberTypes,
+		"limit":        limit,
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	updates := make([]update.Update, 0, limit)
+
+	for rows.Next() {
+		upd, err := scanUpdate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		updates = append(updates, upd)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return updates, nil
+}
+
+// Horizon is the position before every update that may still show up: all
+// transactions older than it have settled.
+func (s *Storage) Horizon(ctx context.Context) (update.Cursor, error) {
+	const op = "update.repository.postgres.Horizon"
+
+	sql := `SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`
+
+	var cursor update.Cursor
+
+	if err := s.conn(ctx).QueryRow(ctx, sql).Scan(&cursor.TxID); err != nil {
+		return update.Cursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cursor, nil
+}
+
+func (s *Storage) DeleteOlderThan(ctx context.Context, t time.Time) (int64, error) {
+	const op = "update.repository.postgres.DeleteOlderThan"
+
+	var deleted int64
+
+	err := s.InTx(ctx, func(ctx context.Context) error {
+		sql := `DELETE FROM updates WHERE created_at < @before`
+		args := pgx.NamedArgs{
+			"before": t,
+		}
+
+		tag, err := s.conn(ctx).Exec(ctx, sql, args)
+		if err != nil {
+			return err
+		}
+		deleted = tag.RowsAffected()
+
+		// Periods that ended before the oldest update left can't match any.
+		sql = `DELETE FROM update_memberships WHERE until_id < (SELECT min(id) FROM updates)`
+
+		_, err = s.conn(ctx).Exec(ctx, sql)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted, nil
+}
+
+func scanUpdate(row pgx.Row) (update.Update, error) {
+	var (
+		upd       update.Update
+		userID    *uint64
+		recipient *uint64
+		typ       string
+	)
+
+	err := row.Scan(
+		&upd.ID,
+		&upd.TxID,
+		&upd.Event.ChatID,
+		&userID,
+		&recipient,
+		&typ,
+		&upd.Event.Payload,
+		&upd.Event.Time,
+	)
+	if err != nil {
+		return update.Update{}, err
+	}
+
+	upd.Event.Type = event.Type(typ)
+	if userID != nil {
+		upd.Event.UserID = *userID
+	}
+	if recipient != nil {
+		upd.RecipientUserID = *recipient
+	}
+	upd.Event.Cursor = update.Cursor{TxID: upd.TxID, ID: upd.ID, Time: upd.Event.Time}.String()
+
+	return upd, nil
+}
+
+func nullableID(id uint64) *uint64 {
+	if id == 0 {
+		return nil
+	}
+
+	return &id
+}
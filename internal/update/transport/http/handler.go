@@ -0,0 +1,70 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/auth"
+	"messanger/internal/update"
+	"messanger/internal/update/usecase"
+	"net/http"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+type UpdateHandler struct {
+	log       *slog.Logger
+	updatesUC usecase.UpdatesUC
+}
+
+func New(log *slog.Logger, updatesUC usecase.UpdatesUC) *UpdateHandler {
+	return &UpdateHandler{
+		log:       log,
+		updatesUC: updatesUC,
+	}
+}
+
+func (h *UpdateHandler) Since(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		errDTO := NewErrorDTO(ErrInvalidToken)
+		http.Error(w, errDTO.String(), http.StatusUnauthorized)
+		return
+	}
+	uid := principal.UserID
+
+	req, err := ParseUpdatesReq(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	events, cursor, hasMore, err := h.updatesUC.Since(r.Context(), uid, req.Since, req.Limit)
+	if err != nil {
+		if errors.Is(err, update.ErrInvalidCursor) {
+			errDTO := NewErrorDTO(update.ErrInvalidCursor)
+			http.Error(w, errDTO.String(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, usecase.ErrCursorExpired) {
+			errDTO := NewErrorDTO(usecase.ErrCursorExpired)
+			http.Error(w, errDTO.String(), http.StatusGone)
+			return
+		}
+
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := UpdatesResDTO{
+		Updates: events,
+		Cursor:  cursor,
+		HasMore: hasMore,
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidLimit = errors.New("invalid limit")
+)
+
+type UpdatesReqDTO struct {
+	Since string
+	Limit int
+}
+
+func ParseUpdatesReq(r *http.Request) (UpdatesReqDTO, error) {
+	q := r.URL.Query()
+
+	req := UpdatesReqDTO{
+		Since: q.Get("since"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return UpdatesReqDTO{}, ErrInvalidLimit
+		}
+		req.Limit = limit
+	}
+
+	return req, nil
+}
+
+type ErrorDTO struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+func NewErrorDTO(err error) ErrorDTO {
+	return ErrorDTO{
+		Message: err.Error(),
+		Time:    time.Now(),
+	}
+}
+
+func (e ErrorDTO) String() string {
+	b, _ := json.MarshalIndent(e, "", "    ")
+
+	return string(b)
+}
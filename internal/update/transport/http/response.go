@@ -0,0 +1,9 @@
+package http
+
+import "messanger/internal/event"
+
+type UpdatesResDTO struct {
+	Updates []event.Event `json:"updates"`
+	Cursor  string        `json:"cursor"`
+	HasMore bool          `json:"has_more"`
+}
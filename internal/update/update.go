@@ -0,0 +1,81 @@
+package update
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"messanger/internal/event"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 500
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Update is an event stored in the update log. TxID is the transaction that
+// appended it. RecipientUserID is set for events that are visible to a
+// single user only.
+type Update struct {
+	ID              uint64
+	TxID            uint64
+	RecipientUserID uint64
+	Event           event.Event
+}
+
+// Cursor is a position in the update log. It is handed out to clients
+// base64-encoded and must be treated by them as opaque.
+//
+// The log is ordered by the appending transaction and then by ID: IDs are
+// taken before commit, so they don't become visible in order, but once
+// every transaction older than TxID has settled nothing can show up before
+// the position anymore.
+type Cursor struct {
+	TxID uint64
+	ID   uint64
+	Time time.Time
+}
+
+func (c Cursor) String() string {
+	raw := fmt.Sprintf("%d:%d:%d", c.TxID, c.ID, c.Time.Unix())
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var (
+		txID uint64
+		id   uint64
+		unix int64
+	)
+
+	switch strings.Count(string(raw), ":") {
+	case 2:
+		if _, err := fmt.Sscanf(string(raw), "%d:%d:%d", &txID, &id, &unix); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	case 1:
+		// Cursors from before the log was ordered by transaction have no
+		// position in it. The zero time makes them expire and the client
+		// resyncs.
+		if _, err := fmt.Sscanf(string(raw), "%d:%d", &id, &unix); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+
+		return Cursor{ID: id}, nil
+	default:
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{TxID: txID, ID: id, Time: time.Unix(unix, 0)}, nil
+}
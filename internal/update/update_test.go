@@ -0,0 +1,40 @@
+package update
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCursor(t *testing.T) {
+	at := time.Unix(1792300000, 0)
+
+	tests := []struct {
+		name    string
+		s       string
+		want    Cursor
+		wantErr error
+	}{
+		{"round trip", Cursor{TxID: 900, ID: 42, Time: at}.String(), Cursor{TxID: 900, ID: 42, Time: at}, nil},
+		{"horizon", Cursor{TxID: 900, Time: at}.String(), Cursor{TxID: 900, Time: at}, nil},
+		{"legacy cursor expires", base64.RawURLEncoding.EncodeToString([]byte("42:1792300000")), Cursor{ID: 42}, nil},
+		{"not base64", "!!", Cursor{}, ErrInvalidCursor},
+		{"garbage", base64.RawURLEncoding.EncodeToString([]byte("a:b:c")), Cursor{}, ErrInvalidCursor},
+		{"too many fields", base64.RawURLEncoding.EncodeToString([]byte("1:2:3:4")), Cursor{}, ErrInvalidCursor},
+		{"empty", "", Cursor{}, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCursor(tt.s)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseCursor(%q) error = %v, want %v", tt.s, err, tt.wantErr)
+			}
+
+			if got.TxID != tt.want.TxID || got.ID != tt.want.ID || !got.Time.Equal(tt.want.Time) {
+				t.Errorf("ParseCursor(%q) = %+v, want %+v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
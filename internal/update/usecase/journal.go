@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/pgtx"
+	"messanger/internal/update/repository"
+)
+
+// Journal is an event.Publisher that stores every event in the update log
+// before passing it on, so that pushed events carry the same cursor clients
+// resume from with GET /updates. Within a transaction of the domain
+// repositories the event is stored in it, and passed on once it commits.
+type Journal struct {
+	log        *slog.Logger
+	updateRepo repository.UpdateWriter
+	next       event.Publisher
+}
+
+func NewJournal(log *slog.Logger, updateRepo repository.UpdateWriter, next event.Publisher) *Journal {
+	return &Journal{
+		log:        log,
+		updateRepo: updateRepo,
+		next:       next,
+	}
+}
+
+// Publish fails if the event can't be stored, the caller's transaction
+// should roll back then so that no change goes unlogged.
+func (j *Journal) Publish(ctx context.Context, ev event.Event) error {
+	const op = "update.usecase.journal.Publish"
+
+	log := j.log.With(
+		slog.String("op", op),
+		slog.String("type", string(ev.Type)),
+		slog.Uint64("chat_id", ev.ChatID),
+	)
+
+	cursor, err := j.updateRepo.Append(ctx, ev)
+	if err != nil {
+		log.Error("failed to journal event", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ev.Cursor = cursor.String()
+
+	pgtx.AfterCommit(ctx, func() {
+		if err := j.next.Publish(ctx, ev); err != nil {
+			log.Error("failed to publish event", sl.Err(err))
+		}
+	})
+
+	return nil
+}
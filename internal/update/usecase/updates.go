@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/event"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/update"
+	"messanger/internal/update/repository"
+	"time"
+)
+
+const retentionPeriod = time.Hour
+
+var (
+	ErrCursorExpired = errors.New("cursor expired")
+)
+
+type UpdatesUC interface {
+	// Since returns events after the cursor, the cursor to continue from and
+	// whether more events are available. An empty cursor returns no events
+	// and the current position of the log.
+	Since(ctx context.Context, userID uint64, cursor string, limit int) ([]event.Event, string, bool, error)
+}
+
+type Updates struct {
+	log        *slog.Logger
+	updateRepo repository.UpdateRepo
+	retention  time.Duration
+}
+
+func NewUpdates(log *slog.Logger, updateRepo repository.UpdateRepo, retention time.Duration) *Updates {
+	return &Updates{
+		log:        log,
+		updateRepo: updateRepo,
+		retention:  retention,
+	}
+}
+
+func (u *Updates) Since(ctx context.Context, userID uint64, cursor string, limit int) ([]event.Event, string, bool, error) {
+	const op = "update.usecase.updates.Since"
+
+	log := u.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	if cursor == "" {
+		current, err := u.current(ctx)
+		if err != nil {
+			log.Error("failed to get current cursor", sl.Err(err))
+			return nil, "", false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return []event.Event{}, current.String(), false, nil
+	}
+
+	since, err := update.ParseCursor(cursor)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Since(since.Time) > u.retention {
+		log.Info("cursor expired", slog.Time("cursor_time", since.Time))
+		return nil, "", false, fmt.Errorf("%s: %w", op, ErrCursorExpired)
+	}
+
+	if limit <= 0 {
+		limit = update.DefaultLimit
+	}
+	if limit > update.MaxLimit {
+		limit = update.MaxLimit
+	}
+
+	updates, err := u.updateRepo.ListSince(ctx, userID, since, limit+1)
+	if err != nil {
+		log.Error("failed to list updates", sl.Err(err))
+		return nil, "", false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hasMore := len(updates) > limit
+	if hasMore {
+		updates = updates[:limit]
+	}
+
+	next := since
+	events := make([]event.Event, 0, len(updates))
+
+	for _, upd := range updates {
+		events = append(events, upd.Event)
+		next = update.Cursor{TxID: upd.TxID, ID: upd.ID, Time: upd.Event.Time}
+	}
+
+	// An idle client should not see its cursor expire just because nothing
+	// happened: move the cursor's clock forward once the log is drained.
+	if !hasMore {
+		next.Time = time.Now()
+	}
+
+	return events, next.String(), hasMore, nil
+}
+
+func (u *Updates) current(ctx context.Context) (update.Cursor, error) {
+	cursor, err := u.updateRepo.Horizon(ctx)
+	if err != nil {
+		return update.Cursor{}, err
+	}
+	cursor.Time = time.Now()
+
+	return cursor, nil
+}
+
+// RunRetention periodically drops updates older than the retention period
+// until ctx is cancelled.
+func (u *Updates) RunRetention(ctx context.Context) {
+	const op = "update.usecase.updates.RunRetention"
+
+	log := u.log.With(
+		slog.String("op", op),
+	)
+
+	ticker := time.NewTicker(retentionPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := u.updateRepo.DeleteOlderThan(ctx, time.Now().Add(-u.retention))
+			if err != nil {
+				log.Error("failed to delete old updates", sl.Err(err))
+				continue
+			}
+
+			log.Debug("old updates deleted", slog.Int64("count", n))
+		}
+	}
+}
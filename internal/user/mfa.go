@@ -0,0 +1,94 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount = 10
+	// recoveryCodeLen is in base32 characters, 50 bits.
+	recoveryCodeLen = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP is a user's authenticator secret. It's pending until the user
+// confirms it with a first code, only a confirmed secret is asked for on
+// login.
+type TOTP struct {
+	UserID uint64
+	Secret string
+	// LastStep is the time step of the last accepted code, a code of that or
+	// an earlier step is not accepted again.
+	LastStep    int64
+	CreatedAt   time.Time
+	ConfirmedAt *time.Time
+}
+
+func (t TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// TOTPEnrollment is shown to the user once, to add the secret to an
+// authenticator app.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAChallenge is issued by a login with a correct password when the user
+// has 2FA on, it's exchanged for a session together with a code.
+type MFAChallenge struct {
+	ID        uint64
+	UserID    uint64
+	Device    Device
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// LoginResult is either a token pair or, when the user has 2FA on, the
+// token of a challenge to complete with a code.
+type LoginResult struct {
+	Tokens       TokenPair
+	MFAToken     string
+	MFAExpiresAt time.Time
+}
+
+func (r LoginResult) MFARequired() bool {
+	return r.MFAToken != ""
+}
+
+// NewRecoveryCodes returns a set of one-time recovery codes and the hashes to
+// store instead of them.
+func NewRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		b := make([]byte, recoveryCodeLen)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:recoveryCodeLen]
+		code = code[:recoveryCodeLen/2] + "-" + code[recoveryCodeLen/2:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode hashes a recovery code the way the user might type it,
+// case and separators don't matter.
+func HashRecoveryCode(code string) []byte {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	return HashToken(code)
+}
@@ -5,4 +5,17 @@ import "errors"
 var (
 	ErrUserNotFound     = errors.New("user not found")
 	ErrUserAlreadyExist = errors.New("user already exist")
+	ErrTokenNotFound    = errors.New("refresh token not found")
+	ErrTokenExpired     = errors.New("refresh token expired")
+	ErrTokenReused      = errors.New("refresh token reused")
+	ErrSessionRevoked   = errors.New("session revoked")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrResetNotFound    = errors.New("reset token is invalid or expired")
+	ErrVerifyNotFound   = errors.New("verification token is invalid or expired")
+	ErrEmailTaken       = errors.New("email is taken")
+	ErrTOTPNotFound     = errors.New("totp is not set up")
+	ErrTOTPEnabled      = errors.New("totp is already enabled")
+	ErrCodeReplayed     = errors.New("code has already been used")
+	ErrRecoveryNotFound = errors.New("recovery code is invalid or used")
+	ErrMFANotFound      = errors.New("mfa challenge is invalid or expired")
 )
\ No newline at end of file
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"messanger/internal/user"
+	"time"
 )
 
 type UserRepo interface {
@@ -10,13 +11,98 @@ type UserRepo interface {
 	UserWriter
 }
 
+type TokenRepo interface {
+	// CreateSession starts a session with its first refresh token.
+	CreateSession(ctx context.Context, userID uint64, device user.Device, tokenHash []byte, ttl time.Duration) (uint64, error)
+	// RotateRefreshToken exchanges a valid refresh token for a new one and
+	// returns the exchanged token. A token that has already been rotated is
+	// returned with ErrTokenReused, its session must be revoked. The
+	// session's user agent, IP and last-seen time are updated.
+	RotateRefreshToken(ctx context.Context, oldHash []byte, newHash []byte, ttl time.Duration, device user.Device) (user.RefreshToken, error)
+}
+
 type UserReader interface {
+	// GetByID never returns the password hash.
 	GetByID(ctx context.Context, id uint64) (user.User, error)
+	// GetByLogin returns the password hash for authentication.
 	GetByLogin(ctx context.Context, login string) (user.User, error)
+	GetPasswordHash(ctx context.Context, id uint64) ([]byte, error)
 }
 
 type UserWriter interface {
 	Create(ctx context.Context, user user.User) (uint64, error)
-	Update(ctx context.Context, newUser user.User) error
-	Delete(ctx context.Context, id uint64) error
+	// Update applies a partial update of the profile and returns the updated
+	// user. A taken login is ErrUserAlreadyExist.
+	Update(ctx context.Context, id uint64, upd user.ProfileUpdate) (user.User, error)
+	SetPasswordHash(ctx context.Context, id uint64, hash []byte) error
+	// Delete marks the user deleted and returns when the account is going to
+	// be purged. A user that is already deleted is ErrUserNotFound.
+	Delete(ctx context.Context, id uint64, grace time.Duration) (time.Time, error)
+	// Restore undoes Delete of a user that hasn't been purged yet.
+	Restore(ctx context.Context, id uint64) error
+	// PurgeDeleted anonymizes the users whose grace period is over, deletes
+	// their sessions and 2FA, and returns their IDs. The rows stay for the
+	// messages they wrote.
+	PurgeDeleted(ctx context.Context) ([]uint64, error)
+}
+
+type SessionRepo interface {
+	// TouchSession updates the last-seen time of an active session and
+	// returns its user. Revoked and missing sessions are ErrSessionNotFound.
+	TouchSession(ctx context.Context, sessionID uint64) (uint64, error)
+	// ListSessions returns the user's active sessions, most recent first.
+	ListSessions(ctx context.Context, userID uint64) ([]user.Session, error)
+	RevokeSession(ctx context.Context, userID uint64, sessionID uint64) error
+	// RevokeSessionByToken revokes the session the refresh token belongs to
+	// and returns it. A token of a revoked session is ErrTokenNotFound.
+	RevokeSessionByToken(ctx context.Context, tokenHash []byte) (uint64, error)
+	// RevokeOtherSessions revokes all the user's sessions but keepID and
+	// returns the revoked ones.
+	RevokeOtherSessions(ctx context.Context, userID uint64, keepID uint64) ([]uint64, error)
+}
+
+type ResetTokenRepo interface {
+	// CreateResetToken stores a reset token and invalidates the user's
+	// previous ones.
+	CreateResetToken(ctx context.Context, userID uint64, tokenHash []byte, ttl time.Duration) error
+	// ConsumeResetToken marks an unused, unexpired token as used and returns
+	// its user. Any other token is ErrResetNotFound.
+	ConsumeResetToken(ctx context.Context, tokenHash []byte) (uint64, error)
+}
+
+type EmailTokenRepo interface {
+	// CreateEmailToken stores a token that verifies the email for the user
+	// and invalidates the user's previous ones.
+	CreateEmailToken(ctx context.Context, userID uint64, email string, tokenHash []byte, ttl time.Duration) error
+	// ConsumeEmailToken marks an unused, unexpired token as used, sets its
+	// email on the user and returns the user. Any other token is
+	// ErrVerifyNotFound, an email another user has verified is ErrEmailTaken.
+	ConsumeEmailToken(ctx context.Context, tokenHash []byte) (uint64, error)
+}
+
+type MFARepo interface {
+	// GetTOTP returns the user's secret, pending or confirmed. A user without
+	// one is ErrTOTPNotFound.
+	GetTOTP(ctx context.Context, userID uint64) (user.TOTP, error)
+	// SaveTOTP stores a pending secret in place of the previous pending one.
+	// A confirmed secret is not replaced, that's ErrTOTPEnabled.
+	SaveTOTP(ctx context.Context, userID uint64, secret string) error
+	// ConfirmTOTP enables the pending secret, with the code of step accepted,
+	// and replaces the user's recovery codes.
+	ConfirmTOTP(ctx context.Context, userID uint64, step int64, codeHashes [][]byte) error
+	// UseTOTPStep accepts a code of the step. If a code of that or a later
+	// step has been accepted it's ErrCodeReplayed.
+	UseTOTPStep(ctx context.Context, userID uint64, step int64) error
+	// UseRecoveryCode marks an unused code as used, any other code is
+	// ErrRecoveryNotFound.
+	UseRecoveryCode(ctx context.Context, userID uint64, codeHash []byte) error
+	// DeleteTOTP turns 2FA off and deletes the recovery codes.
+	DeleteTOTP(ctx context.Context, userID uint64) error
+
+	CreateChallenge(ctx context.Context, userID uint64, tokenHash []byte, device user.Device, ttl time.Duration) error
+	// TakeChallenge counts an attempt at an unexpired challenge and returns
+	// it. Once maxAttempts have been made it's ErrMFANotFound, as is a missing
+	// or expired challenge.
+	TakeChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (user.MFAChallenge, error)
+	DeleteChallenge(ctx context.Context, id uint64) error
 }
@@ -2,29 +2,39 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"messanger/internal/user"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	uniqueViolation = "23505"
 )
 
 type Storage struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
 func New(ctx context.Context, dbURL string) (*Storage, error) {
 	const op = "user.repository.postgres.New"
 
-	conn, err := pgx.Connect(ctx, dbURL)
+	pool, err := pgxpool.New(ctx, dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: conn}, nil
+	return &Storage{db: pool}, nil
 }
 
 func (s *Storage) Close(ctx context.Context) error {
-	return s.db.Close(ctx)
+	s.db.Close()
+
+	return nil
 }
 
 func (s *Storage) Create(ctx context.Context, user user.User) (uint64, error) {
@@ -41,61 +51,200 @@ func (s *Storage) Create(ctx context.Context, user user.User) (uint64, error) {
 
 	err := s.db.QueryRow(ctx, sql, args).Scan(&usrID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, fmt.Errorf("%s: %w", op, ErrUserAlreadyExist)
+		}
+
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return usrID, nil
 }
 
-func (s *Storage) Update(ctx context.Context, newUser user.User) error {
+func (s *Storage) Update(ctx context.Context, id uint64, upd user.ProfileUpdate) (user.User, error) {
 	const op = "user.repository.postgres.Update"
 
-	_, err := s.GetByID(ctx, newUser.ID)
+	sql := `UPDATE users SET
+			name = COALESCE(@name, name),
+			bio = COALESCE(@bio, bio),
+			avatar = COALESCE(@avatar, avatar),
+			login = COALESCE(@login, login)
+		WHERE id = @id
+		RETURNING id, name, login, COALESCE(email, ''), bio, avatar, created_at, deleted_at, purge_at`
+	args := pgx.NamedArgs{
+		"id":     id,
+		"name":   upd.Name,
+		"bio":    upd.Bio,
+		"avatar": upd.Avatar,
+		"login":  upd.Login,
+	}
+
+	var usr user.User
+
+	err := s.db.QueryRow(ctx, sql, args).Scan(
+		&usr.ID,
+		&usr.Name,
+		&usr.Login,
+		&usr.Email,
+		&usr.Bio,
+		&usr.Avatar,
+		&usr.CreatedAt,
+		&usr.DeletedAt,
+		&usr.PurgeAt,
+	)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return user.User{}, fmt.Errorf("%s: %w", op, ErrUserAlreadyExist)
+		}
+
+		return user.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	sql := `UPDATE users SET name = @name, login = @login, password_hash = @password_hash WHERE id = @id`
+	return usr, nil
+}
+
+func (s *Storage) SetPasswordHash(ctx context.Context, id uint64, hash []byte) error {
+	const op = "user.repository.postgres.SetPasswordHash"
+
+	sql := `UPDATE users SET password_hash = @password_hash WHERE id = @id`
 	args := pgx.NamedArgs{
-		"id":            newUser.ID,
-		"name":          newUser.Name,
-		"login":         newUser.Login,
-		"password_hash": newUser.PasswordHash,
+		"id":            id,
+		"password_hash": hash,
 	}
 
-	_, err = s.db.Exec(ctx, sql, args)
+	tag, err := s.db.Exec(ctx, sql, args)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
 	return nil
 }
 
-func (s *Storage) Delete(ctx context.Context, id uint64) error {
+func (s *Storage) Delete(ctx context.Context, id uint64, grace time.Duration) (time.Time, error) {
 	const op = "user.repository.postgres.Delete"
 
-	_, err := s.GetByID(ctx, id)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	sql := `UPDATE users SET deleted_at = current_timestamp, purge_at = current_timestamp + @grace::interval
+		WHERE id = @id AND deleted_at IS NULL
+		RETURNING purge_at`
+	args := pgx.NamedArgs{
+		"id":    id,
+		"grace": grace,
+	}
+
+	var purgeAt time.Time
+
+	if err := s.db.QueryRow(ctx, sql, args).Scan(&purgeAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	sql := `DELETE FROM users WHERE id = @id`
+	return purgeAt, nil
+}
+
+func (s *Storage) Restore(ctx context.Context, id uint64) error {
+	const op = "user.repository.postgres.Restore"
+
+	sql := `UPDATE users SET deleted_at = NULL, purge_at = NULL
+		WHERE id = @id AND deleted_at IS NOT NULL AND purged_at IS NULL`
 	args := pgx.NamedArgs{
 		"id": id,
 	}
 
-	_, err = s.db.Exec(ctx, sql, args)
+	tag, err := s.db.Exec(ctx, sql, args)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
 	return nil
 }
 
+func (s *Storage) PurgeDeleted(ctx context.Context) ([]uint64, error) {
+	const op = "user.repository.postgres.PurgeDeleted"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The login gets a placeholder, it's unique and frees the old one, the
+	// prefix is reserved at registration and on profile updates.
+	sql := `UPDATE users SET
+			name = @name,
+			login = @login_prefix::text || id,
+			email = NULL,
+			bio = '',
+			avatar = '',
+			password_hash = '',
+			purged_at = current_timestamp
+		WHERE purge_at <= current_timestamp AND purged_at IS NULL
+		RETURNING id`
+	args := pgx.NamedArgs{
+		"name":         user.DeletedName,
+		"login_prefix": user.DeletedLoginPrefix,
+	}
+
+	rows, err := tx.Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uint64])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	for _, table := range []string{
+		"auth_sessions",
+		"password_reset_tokens",
+		"email_verification_tokens",
+		"user_totp",
+		"recovery_codes",
+		"mfa_challenges",
+	} {
+		sql := `DELETE FROM ` + table + ` WHERE user_id = ANY(@ids)`
+		args := pgx.NamedArgs{
+			"ids": ids,
+		}
+
+		if _, err := tx.Exec(ctx, sql, args); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
 func (s *Storage) GetByID(ctx context.Context, id uint64) (user.User, error) {
 	const op = "user.repository.postgres.GetByID"
 
-	sql := `SELECT id, name, login, created_at FROM users WHERE id = @id`
+	sql := `SELECT id, name, login, COALESCE(email, ''), bio, avatar, created_at, deleted_at, purge_at
+		FROM users WHERE id = @id`
 	args := pgx.NamedArgs{
 		"id": id,
 	}
@@ -106,9 +255,18 @@ func (s *Storage) GetByID(ctx context.Context, id uint64) (user.User, error) {
 		&usr.ID,
 		&usr.Name,
 		&usr.Login,
+		&usr.Email,
+		&usr.Bio,
+		&usr.Avatar,
 		&usr.CreatedAt,
+		&usr.DeletedAt,
+		&usr.PurgeAt,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
 		return user.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -118,7 +276,8 @@ func (s *Storage) GetByID(ctx context.Context, id uint64) (user.User, error) {
 func (s *Storage) GetByLogin(ctx context.Context, login string) (user.User, error) {
 	const op = "user.repository.postgres.GetByLogin"
 
-	sql := `SELECT id, name, login, password_hash, created_at FROM users WHERE login = @login`
+	sql := `SELECT id, name, login, COALESCE(email, ''), bio, avatar, password_hash, created_at, deleted_at, purge_at
+		FROM users WHERE login = @login`
 	args := pgx.NamedArgs{
 		"login": login,
 	}
@@ -129,12 +288,717 @@ func (s *Storage) GetByLogin(ctx context.Context, login string) (user.User, erro
 		&usr.ID,
 		&usr.Name,
 		&usr.Login,
+		&usr.Email,
+		&usr.Bio,
+		&usr.Avatar,
 		&usr.PasswordHash,
 		&usr.CreatedAt,
+		&usr.DeletedAt,
+		&usr.PurgeAt,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
 		return user.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return usr, nil
 }
+
+func (s *Storage) GetPasswordHash(ctx context.Context, id uint64) ([]byte, error) {
+	const op = "user.repository.postgres.GetPasswordHash"
+
+	sql := `SELECT password_hash FROM users WHERE id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	var hash []byte
+
+	if err := s.db.QueryRow(ctx, sql, args).Scan(&hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hash, nil
+}
+
+func (s *Storage) CreateSession(ctx context.Context, userID uint64, device user.Device, tokenHash []byte, ttl time.Duration) (uint64, error) {
+	const op = "user.repository.postgres.CreateSession"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO auth_sessions(user_id, device_name, user_agent, ip)
+		VALUES(@user_id, @device_name, @user_agent, @ip) RETURNING id`
+	args := pgx.NamedArgs{
+		"user_id":     userID,
+		"device_name": device.Name,
+		"user_agent":  device.UserAgent,
+		"ip":          device.IP,
+	}
+
+	var sessionID uint64
+
+	if err := tx.QueryRow(ctx, sql, args).Scan(&sessionID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertRefreshToken(ctx, tx, sessionID, tokenHash, ttl); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessionID, nil
+}
+
+func (s *Storage) RotateRefreshToken(ctx context.Context, oldHash []byte, newHash []byte, ttl time.Duration, device user.Device) (user.RefreshToken, error) {
+	const op = "user.repository.postgres.RotateRefreshToken"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the session serializes concurrent refreshes of one family.
+	sql := `SELECT rt.id, rt.session_id, s.user_id, rt.token_hash, rt.created_at, rt.expires_at, rt.rotated_at,
+			s.revoked_at IS NOT NULL, rt.expires_at <= current_timestamp
+		FROM refresh_tokens rt
+		JOIN auth_sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = @token_hash
+		FOR UPDATE`
+	args := pgx.NamedArgs{
+		"token_hash": oldHash,
+	}
+
+	var (
+		token   user.RefreshToken
+		revoked bool
+		expired bool
+	)
+
+	err = tx.QueryRow(ctx, sql, args).Scan(
+		&token.ID,
+		&token.SessionID,
+		&token.UserID,
+		&token.Hash,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&token.RotatedAt,
+		&revoked,
+		&expired,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.RefreshToken{}, fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+		}
+
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case revoked:
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, ErrSessionRevoked)
+	case token.RotatedAt != nil:
+		// The token has been stolen or replayed, the caller revokes the
+		// session of the returned token.
+		return token, fmt.Errorf("%s: %w", op, ErrTokenReused)
+	case expired:
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, ErrTokenExpired)
+	}
+
+	sql = `UPDATE refresh_tokens SET rotated_at = current_timestamp WHERE id = @id`
+	args = pgx.NamedArgs{
+		"id": token.ID,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertRefreshToken(ctx, tx, token.SessionID, newHash, ttl); err != nil {
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `UPDATE auth_sessions SET user_agent = @user_agent, ip = @ip, last_seen_at = current_timestamp
+		WHERE id = @id`
+	args = pgx.NamedArgs{
+		"id":         token.SessionID,
+		"user_agent": device.UserAgent,
+		"ip":         device.IP,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return user.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+func (s *Storage) RevokeSessionByToken(ctx context.Context, tokenHash []byte) (uint64, error) {
+	const op = "user.repository.postgres.RevokeSessionByToken"
+
+	sql := `UPDATE auth_sessions SET revoked_at = current_timestamp
+		WHERE id = (SELECT session_id FROM refresh_tokens WHERE token_hash = @token_hash)
+			AND revoked_at IS NULL
+		RETURNING id`
+	args := pgx.NamedArgs{
+		"token_hash": tokenHash,
+	}
+
+	var sessionID uint64
+
+	if err := s.db.QueryRow(ctx, sql, args).Scan(&sessionID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessionID, nil
+}
+
+func (s *Storage) TouchSession(ctx context.Context, sessionID uint64) (uint64, error) {
+	const op = "user.repository.postgres.TouchSession"
+
+	sql := `UPDATE auth_sessions SET last_seen_at = current_timestamp
+		WHERE id = @id AND revoked_at IS NULL
+		RETURNING user_id`
+	args := pgx.NamedArgs{
+		"id": sessionID,
+	}
+
+	var userID uint64
+
+	if err := s.db.QueryRow(ctx, sql, args).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
+
+func (s *Storage) ListSessions(ctx context.Context, userID uint64) ([]user.Session, error) {
+	const op = "user.repository.postgres.ListSessions"
+
+	sql := `SELECT id, user_id, device_name, user_agent, ip, created_at, last_seen_at
+		FROM auth_sessions
+		WHERE user_id = @user_id AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC, id DESC`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	rows, err := s.db.Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []user.Session
+
+	for rows.Next() {
+		var sess user.Session
+
+		err := rows.Scan(
+			&sess.ID,
+			&sess.UserID,
+			&sess.Device.Name,
+			&sess.Device.UserAgent,
+			&sess.Device.IP,
+			&sess.CreatedAt,
+			&sess.LastSeenAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		sessions = append(sessions, sess)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+func (s *Storage) RevokeSession(ctx context.Context, userID uint64, sessionID uint64) error {
+	const op = "user.repository.postgres.RevokeSession"
+
+	sql := `UPDATE auth_sessions SET revoked_at = current_timestamp
+		WHERE id = @id AND user_id = @user_id AND revoked_at IS NULL`
+	args := pgx.NamedArgs{
+		"id":      sessionID,
+		"user_id": userID,
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeOtherSessions(ctx context.Context, userID uint64, keepID uint64) ([]uint64, error) {
+	const op = "user.repository.postgres.RevokeOtherSessions"
+
+	sql := `UPDATE auth_sessions SET revoked_at = current_timestamp
+		WHERE user_id = @user_id AND id <> @keep_id AND revoked_at IS NULL
+		RETURNING id`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"keep_id": keepID,
+	}
+
+	rows, err := s.db.Query(ctx, sql, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uint64])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+func (s *Storage) CreateResetToken(ctx context.Context, userID uint64, tokenHash []byte, ttl time.Duration) error {
+	const op = "user.repository.postgres.CreateResetToken"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE password_reset_tokens SET used_at = current_timestamp
+		WHERE user_id = @user_id AND used_at IS NULL`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `INSERT INTO password_reset_tokens(user_id, token_hash, expires_at)
+		VALUES(@user_id, @token_hash, current_timestamp + @ttl::interval)`
+	args = pgx.NamedArgs{
+		"user_id":    userID,
+		"token_hash": tokenHash,
+		"ttl":        ttl,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ConsumeResetToken(ctx context.Context, tokenHash []byte) (uint64, error) {
+	const op = "user.repository.postgres.ConsumeResetToken"
+
+	sql := `UPDATE password_reset_tokens SET used_at = current_timestamp
+		WHERE token_hash = @token_hash AND used_at IS NULL AND expires_at > current_timestamp
+		RETURNING user_id`
+	args := pgx.NamedArgs{
+		"token_hash": tokenHash,
+	}
+
+	var userID uint64
+
+	if err := s.db.QueryRow(ctx, sql, args).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, ErrResetNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
+
+func (s *Storage) CreateEmailToken(ctx context.Context, userID uint64, email string, tokenHash []byte, ttl time.Duration) error {
+	const op = "user.repository.postgres.CreateEmailToken"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE email_verification_tokens SET used_at = current_timestamp
+		WHERE user_id = @user_id AND used_at IS NULL`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `INSERT INTO email_verification_tokens(user_id, email, token_hash, expires_at)
+		VALUES(@user_id, @email, @token_hash, current_timestamp + @ttl::interval)`
+	args = pgx.NamedArgs{
+		"user_id":    userID,
+		"email":      email,
+		"token_hash": tokenHash,
+		"ttl":        ttl,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ConsumeEmailToken(ctx context.Context, tokenHash []byte) (uint64, error) {
+	const op = "user.repository.postgres.ConsumeEmailToken"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE email_verification_tokens SET used_at = current_timestamp
+		WHERE token_hash = @token_hash AND used_at IS NULL AND expires_at > current_timestamp
+		RETURNING user_id, email`
+	args := pgx.NamedArgs{
+		"token_hash": tokenHash,
+	}
+
+	var (
+		userID uint64
+		email  string
+	)
+
+	if err := tx.QueryRow(ctx, sql, args).Scan(&userID, &email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, ErrVerifyNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A deleted account doesn't get a new address, its tokens are as good
+	// as expired.
+	sql = `UPDATE users SET email = @email WHERE id = @id AND deleted_at IS NULL`
+	args = pgx.NamedArgs{
+		"id":    userID,
+		"email": email,
+	}
+
+	tag, err := tx.Exec(ctx, sql, args)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, fmt.Errorf("%s: %w", op, ErrEmailTaken)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return 0, fmt.Errorf("%s: %w", op, ErrVerifyNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
+
+func (s *Storage) GetTOTP(ctx context.Context, userID uint64) (user.TOTP, error) {
+	const op = "user.repository.postgres.GetTOTP"
+
+	sql := `SELECT user_id, secret, last_step, created_at, confirmed_at FROM user_totp
+		WHERE user_id = @user_id`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	var t user.TOTP
+
+	err := s.db.QueryRow(ctx, sql, args).Scan(&t.UserID, &t.Secret, &t.LastStep, &t.CreatedAt, &t.ConfirmedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.TOTP{}, fmt.Errorf("%s: %w", op, ErrTOTPNotFound)
+		}
+
+		return user.TOTP{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+func (s *Storage) SaveTOTP(ctx context.Context, userID uint64, secret string) error {
+	const op = "user.repository.postgres.SaveTOTP"
+
+	sql := `INSERT INTO user_totp(user_id, secret) VALUES(@user_id, @secret)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = excluded.secret, last_step = 0, created_at = current_timestamp
+		WHERE user_totp.confirmed_at IS NULL`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"secret":  secret,
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTOTPEnabled)
+	}
+
+	return nil
+}
+
+func (s *Storage) ConfirmTOTP(ctx context.Context, userID uint64, step int64, codeHashes [][]byte) error {
+	const op = "user.repository.postgres.ConfirmTOTP"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE user_totp SET confirmed_at = current_timestamp, last_step = @step
+		WHERE user_id = @user_id AND confirmed_at IS NULL`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"step":    step,
+	}
+
+	tag, err := tx.Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTOTPNotFound)
+	}
+
+	sql = `DELETE FROM recovery_codes WHERE user_id = @user_id`
+	args = pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	sql = `INSERT INTO recovery_codes(user_id, code_hash) SELECT @user_id, unnest(@code_hashes::bytea[])`
+	args = pgx.NamedArgs{
+		"user_id":     userID,
+		"code_hashes": codeHashes,
+	}
+
+	if _, err := tx.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, userID uint64, step int64) error {
+	const op = "user.repository.postgres.UseTOTPStep"
+
+	sql := `UPDATE user_totp SET last_step = @step
+		WHERE user_id = @user_id AND confirmed_at IS NOT NULL AND last_step < @step`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"step":    step,
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrCodeReplayed)
+	}
+
+	return nil
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID uint64, codeHash []byte) error {
+	const op = "user.repository.postgres.UseRecoveryCode"
+
+	sql := `UPDATE recovery_codes SET used_at = current_timestamp
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = @user_id AND code_hash = @code_hash AND used_at IS NULL
+			LIMIT 1
+		) AND used_at IS NULL`
+	args := pgx.NamedArgs{
+		"user_id":   userID,
+		"code_hash": codeHash,
+	}
+
+	tag, err := s.db.Exec(ctx, sql, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrRecoveryNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteTOTP(ctx context.Context, userID uint64) error {
+	const op = "user.repository.postgres.DeleteTOTP"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = @user_id`, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTOTPNotFound)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = @user_id`, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CreateChallenge(ctx context.Context, userID uint64, tokenHash []byte, device user.Device, ttl time.Duration) error {
+	const op = "user.repository.postgres.CreateChallenge"
+
+	sql := `INSERT INTO mfa_challenges(user_id, token_hash, device_name, user_agent, ip, expires_at)
+		VALUES(@user_id, @token_hash, @device_name, @user_agent, @ip, current_timestamp + @ttl::interval)`
+	args := pgx.NamedArgs{
+		"user_id":     userID,
+		"token_hash":  tokenHash,
+		"device_name": device.Name,
+		"user_agent":  device.UserAgent,
+		"ip":          device.IP,
+		"ttl":         ttl,
+	}
+
+	if _, err := s.db.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) TakeChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (user.MFAChallenge, error) {
+	const op = "user.repository.postgres.TakeChallenge"
+
+	sql := `UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = @token_hash AND expires_at > current_timestamp AND attempts < @max_attempts
+		RETURNING id, user_id, device_name, user_agent, ip, attempts, created_at, expires_at`
+	args := pgx.NamedArgs{
+		"token_hash":   tokenHash,
+		"max_attempts": maxAttempts,
+	}
+
+	var c user.MFAChallenge
+
+	err := s.db.QueryRow(ctx, sql, args).Scan(
+		&c.ID, &c.UserID, &c.Device.Name, &c.Device.UserAgent, &c.Device.IP, &c.Attempts, &c.CreatedAt, &c.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.MFAChallenge{}, fmt.Errorf("%s: %w", op, ErrMFANotFound)
+		}
+
+		return user.MFAChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+func (s *Storage) DeleteChallenge(ctx context.Context, id uint64) error {
+	const op = "user.repository.postgres.DeleteChallenge"
+
+	sql := `DELETE FROM mfa_challenges WHERE id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	if _, err := s.db.Exec(ctx, sql, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func insertRefreshToken(ctx context.Context, tx pgx.Tx, sessionID uint64, tokenHash []byte, ttl time.Duration) error {
+	sql := `INSERT INTO refresh_tokens(session_id, token_hash, expires_at)
+		VALUES(@session_id, @token_hash, current_timestamp + @ttl::interval)`
+	args := pgx.NamedArgs{
+		"session_id": sessionID,
+		"token_hash": tokenHash,
+		"ttl":        ttl,
+	}
+
+	_, err := tx.Exec(ctx, sql, args)
+
+	return err
+}
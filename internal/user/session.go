@@ -0,0 +1,21 @@
+package user
+
+import "time"
+
+// Device describes where a login comes from.
+type Device struct {
+	Name      string
+	UserAgent string
+	IP        string
+}
+
+// Session is one login of a user. Access tokens carry its ID and stop being
+// accepted once the session is revoked.
+type Session struct {
+	ID         uint64
+	UserID     uint64
+	Device     Device
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
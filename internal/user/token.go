@@ -0,0 +1,48 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+const opaqueTokenSize = 32
+
+// TokenPair is issued on login and on every refresh. The refresh token is
+// opaque and is shown to the client only once.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// RefreshToken is a stored refresh token. Tokens of one session form a
+// family, a token is rotated when it's exchanged for a new one.
+type RefreshToken struct {
+	ID        uint64
+	SessionID uint64
+	UserID    uint64
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+}
+
+// NewOpaqueToken returns a random token, for refresh and password reset, and
+// the hash to store instead of it.
+func NewOpaqueToken() (string, []byte, error) {
+	b := make([]byte, opaqueTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
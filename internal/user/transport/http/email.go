@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/user/usecase"
+	"net/http"
+)
+
+// SetEmail sends a verification token to the new address, the email is
+// changed once the token is confirmed.
+func (h *UserHandler) SetEmail(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.SetEmail"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	var emailDTO SetEmailReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&emailDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := emailDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.email.RequestVerification(r.Context(), principal.UserID, emailDTO.Email, emailDTO.CurrentPassword)
+	if err != nil {
+		h.writeEmailError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.VerifyEmail"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var verifyDTO VerifyEmailReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&verifyDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := verifyDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.email.Verify(r.Context(), verifyDTO.Token); err != nil {
+		h.writeEmailError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) writeEmailError(w http.ResponseWriter, err error) {
+	var status int
+
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		err, status = usecase.ErrInvalidCredentials, http.StatusForbidden
+	case errors.Is(err, usecase.ErrInvalidEmailToken):
+		err, status = usecase.ErrInvalidEmailToken, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrEmailTaken):
+		err, status = usecase.ErrEmailTaken, http.StatusConflict
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	errDTO := NewErrorDTO(err)
+	http.Error(w, errDTO.String(), status)
+}
@@ -4,24 +4,46 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
-	"messanger/internal/lib/jwt"
 	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/user"
 	"messanger/internal/user/repository"
 	"messanger/internal/user/usecase"
+	"net"
 	"net/http"
+	"unicode/utf8"
 )
 
 type UserHandler struct {
-	log     *slog.Logger
-	auth    usecase.AuthUC
-	profile usecase.ProfileUC
+	log      *slog.Logger
+	auth     usecase.AuthUC
+	profile  usecase.ProfileUC
+	sessions usecase.SessionsUC
+	password usecase.PasswordUC
+	email    usecase.EmailUC
+	mfa      usecase.MFAUC
+	limits   Limits
 }
 
-func NewUserHandler(log *slog.Logger, auth usecase.AuthUC, profile usecase.ProfileUC) *UserHandler {
+func NewUserHandler(
+	log *slog.Logger,
+	auth usecase.AuthUC,
+	profile usecase.ProfileUC,
+	sessions usecase.SessionsUC,
+	password usecase.PasswordUC,
+	email usecase.EmailUC,
+	mfa usecase.MFAUC,
+	limits Limits,
+) *UserHandler {
 	return &UserHandler{
-		log:     log,
-		auth:    auth,
-		profile: profile,
+		log:      log,
+		auth:     auth,
+		profile:  profile,
+		sessions: sessions,
+		password: password,
+		email:    email,
+		mfa:      mfa,
+		limits:   limits,
 	}
 }
 
@@ -48,6 +70,13 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Every registration counts, so that accounts can't be created in bulk
+	// from one address.
+	ipKey := limitKey{h.limits.RegisterIP, device(r, "").IP}
+	if !h.allow(w, r, ipKey) {
+		return
+	}
+
 	uid, err := h.auth.Register(r.Context(), registerDTO.Name, registerDTO.Login, registerDTO.Password)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserAlreadyExist) {
@@ -56,6 +85,13 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		var policyErr *passwd.PolicyError
+		if errors.As(err, &policyErr) {
+			errDTO := NewErrorDTO(policyErr)
+			http.Error(w, errDTO.String(), http.StatusBadRequest)
+			return
+		}
+
 		errDTO := NewErrorDTO(err)
 		http.Error(w, errDTO.String(), http.StatusInternalServerError)
 		return
@@ -90,54 +126,133 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.auth.Login(r.Context(), loginReqDTO.Login, loginReqDTO.Password)
+	dev := device(r, loginReqDTO.DeviceName)
+
+	// The attempt is counted before the password is checked, a successful
+	// login clears the count of the login but not of the address.
+	ipKey := limitKey{h.limits.LoginIP, dev.IP}
+	loginKey := limitKey{h.limits.Login, loginReqDTO.Login}
+	if !h.allow(w, r, ipKey, loginKey) {
+		return
+	}
+
+	res, err := h.auth.Login(r.Context(), loginReqDTO.Login, loginReqDTO.Password, dev)
 	if err != nil {
+		if errors.Is(err, usecase.ErrAccountDeleted) {
+			h.reset(r, loginKey)
+
+			errDTO := NewErrorDTO(usecase.ErrAccountDeleted)
+			http.Error(w, errDTO.String(), http.StatusForbidden)
+			return
+		}
+
 		errDTO := NewErrorDTO(usecase.ErrInvalidCredentials)
 		http.Error(w, errDTO.String(), http.StatusBadRequest)
 		return
 	}
 
-	resp := LoginRes{Token: token}
-	json.NewEncoder(w).Encode(resp)
+	h.reset(r, loginKey)
+
+	if res.MFARequired() {
+		json.NewEncoder(w).Encode(NewMFAChallengeRes(res))
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewLoginRes(res.Tokens))
 }
 
-func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	const op = "user.http.handler.Delete"
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.Refresh"
 
 	log := h.log.With(
 		slog.String("op", op),
 	)
 
-	token := jwt.ValidateToken(w, r)
-	if token == nil {
-		log.Warn("token is empty")
-		http.Error(w, "token is empty", http.StatusUnauthorized)
+	var refreshDTO RefreshReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&refreshDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
 		return
 	}
 
-	claims, err := jwt.ParseTokenWithClaims(token)
+	if err := refreshDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	pair, err := h.auth.Refresh(r.Context(), refreshDTO.RefreshToken, device(r, ""))
 	if err != nil {
-		log.Error("token parsing error", sl.Err(err))
-		http.Error(w, "token parsing error", http.StatusBadRequest)
+		if errors.Is(err, usecase.ErrInvalidToken) {
+			errDTO := NewErrorDTO(usecase.ErrInvalidToken)
+			http.Error(w, errDTO.String(), http.StatusUnauthorized)
+			return
+		}
+
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusInternalServerError)
 		return
 	}
 
-	log.Debug("claims", slog.Any("obj", claims))
+	json.NewEncoder(w).Encode(NewLoginRes(pair))
+}
 
-	uid, ok := claims["uid"]
-	if !ok {
-		log.Warn("id not found", slog.Any("uid", claims["uid"]))
-		http.Error(w, "id not found", http.StatusBadRequest)
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.Logout"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var refreshDTO RefreshReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&refreshDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := refreshDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
 		return
 	}
-	uidNum, ok := uid.(float64)
+
+	if err := h.auth.Logout(r.Context(), refreshDTO.RefreshToken); err != nil {
+		if errors.Is(err, usecase.ErrInvalidToken) {
+			errDTO := NewErrorDTO(usecase.ErrInvalidToken)
+			http.Error(w, errDTO.String(), http.StatusUnauthorized)
+			return
+		}
+
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.Delete"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	principal, ok := h.principal(w, r)
 	if !ok {
-		log.Warn("uid is not parsed to int", slog.Any("uid", uid))
-		http.Error(w, "uid is not parsed", http.StatusBadRequest)
+		log.Warn("no principal in context")
 		return
 	}
 
-	if err := h.profile.Delete(r.Context(), uint64(uidNum)); err != nil {
+	purgeAt, err := h.profile.Delete(r.Context(), principal.UserID)
+	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			errDTO := NewErrorDTO(usecase.ErrInvalidCredentials)
 			http.Error(w, errDTO.String(), http.StatusBadRequest)
@@ -149,5 +264,85 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(DeleteRes{PurgeAt: purgeAt})
+}
+
+// Restore brings back a deleted account before it's purged, the user logs
+// in afterwards as usual.
+func (h *UserHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.Restore"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var restoreDTO RestoreReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&restoreDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := restoreDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	ipKey := limitKey{h.limits.LoginIP, device(r, "").IP}
+	loginKey := limitKey{h.limits.Login, restoreDTO.Login}
+	if !h.allow(w, r, ipKey, loginKey) {
+		return
+	}
+
+	if err := h.profile.Restore(r.Context(), restoreDTO.Login, restoreDTO.Password); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidCredentials):
+			errDTO := NewErrorDTO(usecase.ErrInvalidCredentials)
+			http.Error(w, errDTO.String(), http.StatusBadRequest)
+		case errors.Is(err, usecase.ErrNotDeleted):
+			errDTO := NewErrorDTO(usecase.ErrNotDeleted)
+			http.Error(w, errDTO.String(), http.StatusConflict)
+		default:
+			errDTO := NewErrorDTO(err)
+			http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.reset(r, loginKey)
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// device describes the client of the request. The device name is chosen by
+// the client on login.
+func device(r *http.Request, name string) user.Device {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return user.Device{
+		Name:      name,
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+		IP:        ip,
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	// Don't cut a multibyte character in half.
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
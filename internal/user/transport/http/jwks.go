@@ -0,0 +1,18 @@
+package http
+
+import (
+	"encoding/json"
+	"messanger/internal/lib/jwt"
+	"net/http"
+)
+
+// JWKS serves the public keys that verify access tokens, for other services
+// to validate them without a shared secret.
+func JWKS(keyring *jwt.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+
+		json.NewEncoder(w).Encode(keyring.JWKS())
+	}
+}
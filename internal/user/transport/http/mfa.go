@@ -0,0 +1,168 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/user/usecase"
+	"net/http"
+)
+
+// LoginMFA exchanges the token of a login challenge and a TOTP or recovery
+// code for a session.
+func (h *UserHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.LoginMFA"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var loginDTO LoginMFAReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&loginDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := loginDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	// A challenge allows a few codes only, the per-IP limit keeps codes from
+	// being guessed over many challenges.
+	ipKey := limitKey{h.limits.LoginIP, device(r, "").IP}
+	if !h.allow(w, r, ipKey) {
+		return
+	}
+
+	pair, err := h.auth.LoginMFA(r.Context(), loginDTO.MFAToken, loginDTO.Code)
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewLoginRes(pair))
+}
+
+// EnrollMFA returns a new TOTP secret and its provisioning URI, 2FA is on
+// once the first code is confirmed.
+func (h *UserHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.mfa.Enroll(r.Context(), principal.UserID)
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(EnrollMFARes{
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+	})
+}
+
+func (h *UserHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.ConfirmMFA"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	var confirmDTO ConfirmMFAReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&confirmDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := confirmDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.mfa.Confirm(r.Context(), principal.UserID, confirmDTO.Code)
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+func (h *UserHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.DisableMFA"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	var disableDTO DisableMFAReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&disableDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := disableDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.mfa.Disable(r.Context(), principal.UserID, disableDTO.Password, disableDTO.Code); err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) writeMFAError(w http.ResponseWriter, err error) {
+	var status int
+
+	switch {
+	case errors.Is(err, usecase.ErrInvalidMFAToken):
+		err, status = usecase.ErrInvalidMFAToken, http.StatusUnauthorized
+	case errors.Is(err, usecase.ErrInvalidCode):
+		err, status = usecase.ErrInvalidCode, http.StatusForbidden
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		err, status = usecase.ErrInvalidCredentials, http.StatusForbidden
+	case errors.Is(err, usecase.ErrMFAEnabled):
+		err, status = usecase.ErrMFAEnabled, http.StatusConflict
+	case errors.Is(err, usecase.ErrMFANotEnrolled):
+		err, status = usecase.ErrMFANotEnrolled, http.StatusConflict
+	case errors.Is(err, usecase.ErrMFADisabled):
+		err, status = usecase.ErrMFADisabled, http.StatusConflict
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	errDTO := NewErrorDTO(err)
+	http.Error(w, errDTO.String(), status)
+}
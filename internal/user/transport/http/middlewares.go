@@ -1,22 +1,54 @@
 package http
 
 import (
+	"messanger/internal/lib/auth"
 	"messanger/internal/lib/jwt"
 	"messanger/internal/user/usecase"
 	"net/http"
 )
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := jwt.ValidateToken(w, r)
-		if token == nil {
-			errDTO := NewErrorDTO(usecase.ErrInvalidToken)
-			http.Error(w, errDTO.String(), http.StatusUnauthorized)
-			return
-		}
+// AuthMiddleware verifies the bearer token and puts the authenticated
+// principal into the request context. Tokens of revoked sessions are
+// rejected.
+func AuthMiddleware(keyring *jwt.Keyring, sessions usecase.SessionsUC) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, err := jwt.FromRequest(r)
+			if err != nil {
+				errDTO := NewErrorDTO(usecase.ErrInvalidToken)
+				http.Error(w, errDTO.String(), http.StatusUnauthorized)
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			claims, err := jwt.Parse(tokenString, keyring)
+			if err != nil {
+				errDTO := NewErrorDTO(usecase.ErrInvalidToken)
+				http.Error(w, errDTO.String(), http.StatusUnauthorized)
+				return
+			}
+
+			principal, err := claims.Principal()
+			if err != nil {
+				errDTO := NewErrorDTO(usecase.ErrInvalidToken)
+				http.Error(w, errDTO.String(), http.StatusUnauthorized)
+				return
+			}
+
+			active, err := sessions.Active(r.Context(), principal.UserID, principal.SessionID)
+			if err != nil {
+				errDTO := NewErrorDTO(err)
+				http.Error(w, errDTO.String(), http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				errDTO := NewErrorDTO(usecase.ErrInvalidToken)
+				http.Error(w, errDTO.String(), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
 }
 
 func CorsMiddleware(next http.Handler) http.Handler {
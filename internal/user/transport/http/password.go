@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/user/usecase"
+	"net/http"
+)
+
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.ChangePassword"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	var changeDTO ChangePasswordReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&changeDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := changeDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.password.Change(r.Context(), principal.UserID, principal.SessionID, changeDTO.OldPassword, changeDTO.NewPassword)
+	if err != nil {
+		h.writePasswordError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestReset always answers 202, whether the login exists or not.
+func (h *UserHandler) RequestReset(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.RequestReset"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var resetDTO RequestResetReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&resetDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := resetDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.password.RequestReset(r.Context(), resetDTO.Login); err != nil {
+		h.writePasswordError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.ResetPassword"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var resetDTO ResetPasswordReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&resetDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := resetDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.password.Reset(r.Context(), resetDTO.Token, resetDTO.NewPassword); err != nil {
+		h.writePasswordError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) writePasswordError(w http.ResponseWriter, err error) {
+	var (
+		status    int
+		policyErr *passwd.PolicyError
+	)
+
+	switch {
+	case errors.As(err, &policyErr):
+		err, status = policyErr, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		err, status = usecase.ErrInvalidCredentials, http.StatusForbidden
+	case errors.Is(err, usecase.ErrInvalidResetToken):
+		err, status = usecase.ErrInvalidResetToken, http.StatusBadRequest
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	errDTO := NewErrorDTO(err)
+	http.Error(w, errDTO.String(), status)
+}
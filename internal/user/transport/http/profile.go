@@ -0,0 +1,101 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/user/repository"
+	"messanger/internal/user/usecase"
+	"net/http"
+)
+
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	usr, err := h.profile.Get(r.Context(), principal.UserID)
+	if err != nil {
+		h.writeProfileError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewProfileRes(usr))
+}
+
+// Get returns the public part of another user's profile.
+func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := ParseUserID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	usr, err := h.profile.Get(r.Context(), userID)
+	if err != nil {
+		h.writeProfileError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewPublicProfileRes(usr))
+}
+
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	const op = "user.http.handler.UpdateMe"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	var updateDTO UpdateProfileReqDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&updateDTO); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Warn(errDTO.String(), sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := updateDTO.Validate(); err != nil {
+		errDTO := NewErrorDTO(err)
+		log.Error("validation error", sl.Err(err))
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	usr, err := h.profile.Update(r.Context(), principal.UserID, updateDTO.ProfileUpdate(), updateDTO.CurrentPassword)
+	if err != nil {
+		h.writeProfileError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(NewProfileRes(usr))
+}
+
+func (h *UserHandler) writeProfileError(w http.ResponseWriter, err error) {
+	var status int
+
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		err, status = repository.ErrUserNotFound, http.StatusNotFound
+	case errors.Is(err, repository.ErrUserAlreadyExist):
+		err, status = repository.ErrUserAlreadyExist, http.StatusConflict
+	case errors.Is(err, usecase.ErrPasswordRequired):
+		err, status = usecase.ErrPasswordRequired, http.StatusBadRequest
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		err, status = usecase.ErrInvalidCredentials, http.StatusForbidden
+	default:
+		status = http.StatusInternalServerError
+	}
+
+	errDTO := NewErrorDTO(err)
+	http.Error(w, errDTO.String(), status)
+}
@@ -3,13 +3,50 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"messanger/internal/user"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
 )
 
 var (
 	ErrNameIsEmpty     = errors.New("name is empty")
 	ErrLoginIsEmpty    = errors.New("login is empty")
 	ErrPasswordIsEmpty = errors.New("password is empty")
+	ErrTokenIsEmpty    = errors.New("refresh_token is empty")
+	ErrInvalidSession  = errors.New("invalid session id")
+	ErrInvalidUserID   = errors.New("invalid user id")
+	ErrNothingToApply  = errors.New("nothing to update")
+	ErrLoginReserved   = errors.New("login is reserved")
+
+	ErrResetTokenIsEmpty = errors.New("token is empty")
+	ErrEmailTokenEmpty   = errors.New("token is empty")
+	ErrMFATokenIsEmpty   = errors.New("mfa_token is empty")
+	ErrCodeIsEmpty       = errors.New("code is empty")
+	ErrEmailIsEmpty      = errors.New("email is empty")
+	ErrInvalidEmail      = errors.New("invalid email")
+
+	ErrDeviceNameTooLong = errors.New("device_name is too long")
+	ErrNameTooLong       = errors.New("name is too long")
+	ErrLoginTooLong      = errors.New("login is too long")
+	ErrBioTooLong        = errors.New("bio is too long")
+	ErrAvatarTooLong     = errors.New("avatar is too long")
+	ErrEmailTooLong      = errors.New("email is too long")
+)
+
+const (
+	maxDeviceNameLength = 255
+	maxUserAgentLength  = 512
+	maxNameLength       = 255
+	maxLoginLength      = 255
+	maxBioLength        = 1024
+	maxAvatarLength     = 512
+	maxEmailLength      = 255
 )
 
 type RegisterReqDTO struct {
@@ -22,6 +59,9 @@ func (r RegisterReqDTO) Validate() error {
 	if r.Login == "" {
 		return ErrLoginIsEmpty
 	}
+	if strings.HasPrefix(r.Login, user.DeletedLoginPrefix) {
+		return ErrLoginReserved
+	}
 	if r.Name == "" {
 		return ErrNameIsEmpty
 	}
@@ -32,9 +72,12 @@ func (r RegisterReqDTO) Validate() error {
 	return nil
 }
 
+// LoginReqDTO logs in on a device, DeviceName is optional and is shown in
+// the list of sessions.
 type LoginReqDTO struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login      string `json:"login"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
 }
 
 func (r LoginReqDTO) Validate() error {
@@ -44,6 +87,21 @@ func (r LoginReqDTO) Validate() error {
 	if r.Password == "" {
 		return ErrPasswordIsEmpty
 	}
+	if utf8.RuneCountInString(r.DeviceName) > maxDeviceNameLength {
+		return ErrDeviceNameTooLong
+	}
+
+	return nil
+}
+
+type RefreshReqDTO struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (r RefreshReqDTO) Validate() error {
+	if r.RefreshToken == "" {
+		return ErrTokenIsEmpty
+	}
 
 	return nil
 }
@@ -52,6 +110,214 @@ type DeleteReqDTO struct {
 	ID uint64 `json:"id"`
 }
 
+type RestoreReqDTO struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+func (r RestoreReqDTO) Validate() error {
+	if r.Login == "" {
+		return ErrLoginIsEmpty
+	}
+	if r.Password == "" {
+		return ErrPasswordIsEmpty
+	}
+
+	return nil
+}
+
+// UpdateProfileReqDTO is a partial update, omitted fields are left as they
+// are. Changing login needs current_password.
+type UpdateProfileReqDTO struct {
+	Name            *string `json:"name"`
+	Bio             *string `json:"bio"`
+	Avatar          *string `json:"avatar"`
+	Login           *string `json:"login"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+func (r UpdateProfileReqDTO) Validate() error {
+	if r.Name == nil && r.Bio == nil && r.Avatar == nil && r.Login == nil {
+		return ErrNothingToApply
+	}
+	if r.Name != nil {
+		if *r.Name == "" {
+			return ErrNameIsEmpty
+		}
+		if utf8.RuneCountInString(*r.Name) > maxNameLength {
+			return ErrNameTooLong
+		}
+	}
+	if r.Login != nil {
+		if *r.Login == "" {
+			return ErrLoginIsEmpty
+		}
+		if utf8.RuneCountInString(*r.Login) > maxLoginLength {
+			return ErrLoginTooLong
+		}
+		if strings.HasPrefix(*r.Login, user.DeletedLoginPrefix) {
+			return ErrLoginReserved
+		}
+	}
+	if r.Bio != nil && utf8.RuneCountInString(*r.Bio) > maxBioLength {
+		return ErrBioTooLong
+	}
+	if r.Avatar != nil && utf8.RuneCountInString(*r.Avatar) > maxAvatarLength {
+		return ErrAvatarTooLong
+	}
+
+	return nil
+}
+
+func (r UpdateProfileReqDTO) ProfileUpdate() user.ProfileUpdate {
+	return user.ProfileUpdate{
+		Name:   r.Name,
+		Bio:    r.Bio,
+		Avatar: r.Avatar,
+		Login:  r.Login,
+	}
+}
+
+type ChangePasswordReqDTO struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+func (r ChangePasswordReqDTO) Validate() error {
+	if r.OldPassword == "" || r.NewPassword == "" {
+		return ErrPasswordIsEmpty
+	}
+
+	return nil
+}
+
+type RequestResetReqDTO struct {
+	Login string `json:"login"`
+}
+
+func (r RequestResetReqDTO) Validate() error {
+	if r.Login == "" {
+		return ErrLoginIsEmpty
+	}
+
+	return nil
+}
+
+type ResetPasswordReqDTO struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (r ResetPasswordReqDTO) Validate() error {
+	if r.Token == "" {
+		return ErrResetTokenIsEmpty
+	}
+	if r.NewPassword == "" {
+		return ErrPasswordIsEmpty
+	}
+
+	return nil
+}
+
+// SetEmailReqDTO asks to verify a new email, it takes the current password
+// as the email receives password resets.
+type SetEmailReqDTO struct {
+	Email           string `json:"email"`
+	CurrentPassword string `json:"current_password"`
+}
+
+func (r SetEmailReqDTO) Validate() error {
+	if r.Email == "" {
+		return ErrEmailIsEmpty
+	}
+	if utf8.RuneCountInString(r.Email) > maxEmailLength {
+		return ErrEmailTooLong
+	}
+	// Only a bare address, a display name would end up in the To header.
+	if addr, err := mail.ParseAddress(r.Email); err != nil || addr.Address != r.Email {
+		return ErrInvalidEmail
+	}
+	if r.CurrentPassword == "" {
+		return ErrPasswordIsEmpty
+	}
+
+	return nil
+}
+
+type VerifyEmailReqDTO struct {
+	Token string `json:"token"`
+}
+
+func (r VerifyEmailReqDTO) Validate() error {
+	if r.Token == "" {
+		return ErrEmailTokenEmpty
+	}
+
+	return nil
+}
+
+type LoginMFAReqDTO struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+func (r LoginMFAReqDTO) Validate() error {
+	if r.MFAToken == "" {
+		return ErrMFATokenIsEmpty
+	}
+	if r.Code == "" {
+		return ErrCodeIsEmpty
+	}
+
+	return nil
+}
+
+type ConfirmMFAReqDTO struct {
+	Code string `json:"code"`
+}
+
+func (r ConfirmMFAReqDTO) Validate() error {
+	if r.Code == "" {
+		return ErrCodeIsEmpty
+	}
+
+	return nil
+}
+
+type DisableMFAReqDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (r DisableMFAReqDTO) Validate() error {
+	if r.Password == "" {
+		return ErrPasswordIsEmpty
+	}
+	if r.Code == "" {
+		return ErrCodeIsEmpty
+	}
+
+	return nil
+}
+
+func ParseUserID(r *http.Request) (uint64, error) {
+	userID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || userID == 0 {
+		return 0, ErrInvalidUserID
+	}
+
+	return userID, nil
+}
+
+func ParseSessionID(r *http.Request) (uint64, error) {
+	sessionID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || sessionID == 0 {
+		return 0, ErrInvalidSession
+	}
+
+	return sessionID, nil
+}
+
 type ErrorDTO struct {
 	Message string    `json:"message"`
 	Time    time.Time `json:"time"`
@@ -1,9 +1,124 @@
 package http
 
+import (
+	"messanger/internal/user"
+	"time"
+)
+
 type RegisterRes struct {
 	ID uint64 `json:"id"`
 }
 
+// DeleteRes tells until when a deleted account can be restored.
+type DeleteRes struct {
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// ProfileRes is the caller's own profile.
+type ProfileRes struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	Login     string    `json:"login"`
+	Email     string    `json:"email,omitempty"`
+	Bio       string    `json:"bio"`
+	Avatar    string    `json:"avatar,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewProfileRes(usr user.User) ProfileRes {
+	return ProfileRes{
+		ID:        usr.ID,
+		Name:      usr.Name,
+		Login:     usr.Login,
+		Email:     usr.Email,
+		Bio:       usr.Bio,
+		Avatar:    usr.Avatar,
+		CreatedAt: usr.CreatedAt,
+	}
+}
+
+// PublicProfileRes is what other users see.
+type PublicProfileRes struct {
+	ID     uint64 `json:"id"`
+	Name   string `json:"name"`
+	Bio    string `json:"bio"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+func NewPublicProfileRes(usr user.User) PublicProfileRes {
+	return PublicProfileRes{
+		ID:     usr.ID,
+		Name:   usr.Name,
+		Bio:    usr.Bio,
+		Avatar: usr.Avatar,
+	}
+}
+
 type LoginRes struct {
-	Token string `json:"token"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func NewLoginRes(pair user.TokenPair) LoginRes {
+	return LoginRes{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresAt:    pair.ExpiresAt,
+	}
+}
+
+// MFAChallengeRes answers a login of a user with 2FA on, the token is sent
+// to /user/login/mfa together with a code.
+type MFAChallengeRes struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func NewMFAChallengeRes(res user.LoginResult) MFAChallengeRes {
+	return MFAChallengeRes{
+		MFARequired: true,
+		MFAToken:    res.MFAToken,
+		ExpiresAt:   res.MFAExpiresAt,
+	}
+}
+
+type EnrollMFARes struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type SessionResDTO struct {
+	ID         uint64    `json:"id"`
+	DeviceName string    `json:"device_name,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+func NewSessionResDTO(sess user.Session, currentID uint64) SessionResDTO {
+	return SessionResDTO{
+		ID:         sess.ID,
+		DeviceName: sess.Device.Name,
+		UserAgent:  sess.Device.UserAgent,
+		IP:         sess.Device.IP,
+		CreatedAt:  sess.CreatedAt,
+		LastSeenAt: sess.LastSeenAt,
+		Current:    sess.ID == currentID,
+	}
+}
+
+type ListSessionsRes struct {
+	Sessions []SessionResDTO `json:"sessions"`
+}
+
+type RevokeSessionsRes struct {
+	Revoked int `json:"revoked"`
 }
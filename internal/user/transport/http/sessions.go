@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"messanger/internal/lib/auth"
+	"messanger/internal/user/repository"
+	"messanger/internal/user/usecase"
+	"net/http"
+)
+
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessions.List(r.Context(), principal.UserID)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListSessionsRes{
+		Sessions: make([]SessionResDTO, 0, len(sessions)),
+	}
+	for _, sess := range sessions {
+		resp.Sessions = append(resp.Sessions, NewSessionResDTO(sess, principal.SessionID))
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RevokeSession ends one of the caller's sessions, the current one included.
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := ParseSessionID(r)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sessions.Revoke(r.Context(), principal.UserID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			errDTO := NewErrorDTO(repository.ErrSessionNotFound)
+			http.Error(w, errDTO.String(), http.StatusNotFound)
+			return
+		}
+
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions ends every session of the caller but the current one.
+func (h *UserHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.sessions.RevokeOthers(r.Context(), principal.UserID, principal.SessionID)
+	if err != nil {
+		errDTO := NewErrorDTO(err)
+		http.Error(w, errDTO.String(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(RevokeSessionsRes{Revoked: revoked})
+}
+
+// principal returns the caller put into the context by the auth middleware.
+func (h *UserHandler) principal(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		errDTO := NewErrorDTO(usecase.ErrInvalidToken)
+		http.Error(w, errDTO.String(), http.StatusUnauthorized)
+		return auth.Principal{}, false
+	}
+
+	return principal, true
+}
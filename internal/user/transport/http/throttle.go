@@ -0,0 +1,66 @@
+package http
+
+import (
+	"math"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/throttle"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Limits throttles logins per IP and per login, and registrations per IP.
+// A nil limiter doesn't throttle.
+type Limits struct {
+	Login      *throttle.Limiter
+	LoginIP    *throttle.Limiter
+	RegisterIP *throttle.Limiter
+}
+
+type limitKey struct {
+	limiter *throttle.Limiter
+	key     string
+}
+
+// allow counts an attempt of every key before it is made and answers 429
+// with Retry-After when one of the keys has to wait. A failing store doesn't
+// lock anybody out, the error is only logged.
+func (h *UserHandler) allow(w http.ResponseWriter, r *http.Request, keys ...limitKey) bool {
+	var wait time.Duration
+
+	for _, k := range keys {
+		if k.limiter == nil {
+			continue
+		}
+
+		d, err := k.limiter.Attempt(r.Context(), k.key)
+		if err != nil {
+			h.log.Error("failed to count attempt", sl.Err(err))
+			continue
+		}
+
+		wait = max(wait, d)
+	}
+
+	if wait == 0 {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+
+	errDTO := NewErrorDTO(throttle.ErrThrottled)
+	http.Error(w, errDTO.String(), http.StatusTooManyRequests)
+
+	return false
+}
+
+// reset forgets the attempts of the key.
+func (h *UserHandler) reset(r *http.Request, k limitKey) {
+	if k.limiter == nil {
+		return
+	}
+
+	if err := k.limiter.Reset(r.Context(), k.key); err != nil {
+		h.log.Error("failed to reset attempts", sl.Err(err))
+	}
+}
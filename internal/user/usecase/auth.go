@@ -7,11 +7,10 @@ import (
 	"log/slog"
 	"messanger/internal/lib/jwt"
 	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/passwd"
 	"messanger/internal/user"
 	"messanger/internal/user/repository"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -20,23 +19,55 @@ var (
 )
 
 type AuthUC interface {
-	Login(ctx context.Context, login, password string) (string, error)
+	// Login starts a session, or returns a challenge instead when the user
+	// has 2FA on.
+	Login(ctx context.Context, login, password string, device user.Device) (user.LoginResult, error)
+	// LoginMFA completes a challenge with a TOTP or recovery code and starts
+	// the session on the device of the login.
+	LoginMFA(ctx context.Context, mfaToken, code string) (user.TokenPair, error)
 	Register(ctx context.Context, name, login, password string) (uint64, error)
+	// Refresh rotates the refresh token and issues a new access token.
+	Refresh(ctx context.Context, refreshToken string, device user.Device) (user.TokenPair, error)
+	// Logout revokes the session of the refresh token.
+	Logout(ctx context.Context, refreshToken string) error
 }
 
 type Auth struct {
-	log      *slog.Logger
-	userRepo repository.UserRepo
-	secret   string
-	tokenTTL time.Duration
+	log        *slog.Logger
+	userRepo   repository.UserRepo
+	tokenRepo  repository.TokenRepo
+	mfaRepo    repository.MFARepo
+	sessions   SessionsUC
+	keyring    *jwt.Keyring
+	hasher     *passwd.Hasher
+	policy     passwd.Policy
+	tokenTTL   time.Duration
+	refreshTTL time.Duration
 }
 
-func NewAuth(log *slog.Logger, userRepo repository.UserRepo, secret string, tokenTTL time.Duration) *Auth {
+func NewAuth(
+	log *slog.Logger,
+	userRepo repository.UserRepo,
+	tokenRepo repository.TokenRepo,
+	mfaRepo repository.MFARepo,
+	sessions SessionsUC,
+	keyring *jwt.Keyring,
+	hasher *passwd.Hasher,
+	policy passwd.Policy,
+	tokenTTL time.Duration,
+	refreshTTL time.Duration,
+) *Auth {
 	return &Auth{
-		log:      log,
-		userRepo: userRepo,
-		secret:   secret,
-		tokenTTL: tokenTTL,
+		log:        log,
+		userRepo:   userRepo,
+		tokenRepo:  tokenRepo,
+		mfaRepo:    mfaRepo,
+		sessions:   sessions,
+		keyring:    keyring,
+		hasher:     hasher,
+		policy:     policy,
+		tokenTTL:   tokenTTL,
+		refreshTTL: refreshTTL,
 	}
 }
 
@@ -54,7 +85,11 @@ func (a *Auth) Register(ctx context.Context, name, login, password string) (uint
 		return 0, fmt.Errorf("%s: %w", op, repository.ErrUserAlreadyExist)
 	}
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := a.policy.Validate(password); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := a.hasher.Hash(password)
 	if err != nil {
 		log.Error("hash generation error", sl.Err(err))
 		return 0, fmt.Errorf("%s: %w", op, err)
@@ -71,7 +106,7 @@ func (a *Auth) Register(ctx context.Context, name, login, password string) (uint
 	return id, nil
 }
 
-func (a *Auth) Login(ctx context.Context, login, password string) (string, error) {
+func (a *Auth) Login(ctx context.Context, login, password string, device user.Device) (user.LoginResult, error) {
 	const op = "user.usecase.auth.Login"
 
 	log := a.log.With(
@@ -84,24 +119,226 @@ func (a *Auth) Login(ctx context.Context, login, password string) (string, error
 		if errors.Is(err, repository.ErrUserNotFound) {
 			log.Warn("user not found", sl.Err(err))
 
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			return user.LoginResult{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
 
 		log.Error("failed to get user", sl.Err(err))
-		return "", fmt.Errorf("%s: %w", op, err)
+		return user.LoginResult{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
+	needsRehash, err := a.hasher.Verify(usr.PasswordHash, password)
+	if err != nil {
 		log.Info("invalid credentials", sl.Err(err))
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return user.LoginResult{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	// Checked after the password, so the caller can't tell which accounts
+	// are deleted.
+	if usr.Deleted() {
+		log.Info("login to a deleted account")
+		return user.LoginResult{}, fmt.Errorf("%s: %w", op, ErrAccountDeleted)
+	}
+
+	if needsRehash {
+		a.rehash(ctx, log, usr.ID, password)
+	}
+
+	current, err := a.mfaRepo.GetTOTP(ctx, usr.ID)
+	if err != nil && !errors.Is(err, repository.ErrTOTPNotFound) {
+		log.Error("failed to get totp", sl.Err(err))
+		return user.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if current.Enabled() {
+		mfaToken, mfaHash, err := user.NewOpaqueToken()
+		if err != nil {
+			log.Error("failed to generate mfa token", sl.Err(err))
+			return user.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := a.mfaRepo.CreateChallenge(ctx, usr.ID, mfaHash, device, mfaChallengeTTL); err != nil {
+			log.Error("challenge creation error", sl.Err(err))
+			return user.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return user.LoginResult{
+			MFAToken:     mfaToken,
+			MFAExpiresAt: time.Now().Add(mfaChallengeTTL),
+		}, nil
+	}
+
+	pair, err := a.startSession(ctx, usr, device)
+	if err != nil {
+		log.Error("failed to start session", sl.Err(err))
+		return user.LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user.LoginResult{Tokens: pair}, nil
+}
+
+func (a *Auth) LoginMFA(ctx context.Context, mfaToken, code string) (user.TokenPair, error) {
+	const op = "user.usecase.auth.LoginMFA"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	challenge, err := a.mfaRepo.TakeChallenge(ctx, user.HashToken(mfaToken), mfaMaxAttempts)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return user.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+		}
+
+		log.Error("failed to take challenge", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Uint64("user_id", challenge.UserID))
+
+	current, err := a.mfaRepo.GetTOTP(ctx, challenge.UserID)
+	if err != nil && !errors.Is(err, repository.ErrTOTPNotFound) {
+		log.Error("failed to get totp", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// 2FA was turned off after the challenge was issued, the password alone
+	// is no longer enough to tell the login was meant to go on.
+	if !current.Enabled() {
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+	}
+
+	if err := verifyCode(ctx, a.mfaRepo, current, code); err != nil {
+		log.Info("code rejected", slog.Int("attempt", challenge.Attempts), sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.mfaRepo.DeleteChallenge(ctx, challenge.ID); err != nil {
+		log.Error("failed to delete challenge", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	usr, err := a.userRepo.GetByID(ctx, challenge.UserID)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pair, err := a.startSession(ctx, usr, challenge.Device)
+	if err != nil {
+		log.Error("failed to start session", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pair, nil
+}
+
+func (a *Auth) Refresh(ctx context.Context, refreshToken string, device user.Device) (user.TokenPair, error) {
+	const op = "user.usecase.auth.Refresh"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	newToken, newHash, err := user.NewOpaqueToken()
+	if err != nil {
+		log.Error("failed to generate refresh token", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	old, err := a.tokenRepo.RotateRefreshToken(ctx, user.HashToken(refreshToken), newHash, a.refreshTTL, device)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTokenReused):
+			// Whoever holds the family can't be trusted anymore.
+			log.Warn("refresh token reused, revoking session", sl.Err(err), slog.Uint64("session_id", old.SessionID))
+
+			err := a.sessions.Revoke(ctx, old.UserID, old.SessionID)
+			if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+				log.Error("session revocation error", sl.Err(err))
+				return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+			}
+
+			return user.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		case errors.Is(err, repository.ErrTokenNotFound),
+			errors.Is(err, repository.ErrTokenExpired),
+			errors.Is(err, repository.ErrSessionRevoked):
+			log.Info("refresh rejected", sl.Err(err))
+			return user.TokenPair{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("refresh token rotation error", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	token, err := jwt.NewToken(usr, a.secret, a.tokenTTL)
+	log = log.With(slog.Uint64("user_id", old.UserID), slog.Uint64("session_id", old.SessionID))
+
+	usr, err := a.userRepo.GetByID(ctx, old.UserID)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pair, err := a.issue(usr, old.SessionID, newToken)
 	if err != nil {
-		log.Info("failed to generate token", sl.Err(err))
+		log.Error("failed to generate token", sl.Err(err))
+		return user.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
 
-		return "", fmt.Errorf("%s: %w", op, err)
+	return pair, nil
+}
+
+func (a *Auth) Logout(ctx context.Context, refreshToken string) error {
+	const op = "user.usecase.auth.Logout"
+
+	if err := a.sessions.RevokeByToken(ctx, refreshToken); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// rehash moves the stored hash to the current algorithm and costs. The login
+// succeeds anyway, so a failure is only logged.
+func (a *Auth) rehash(ctx context.Context, log *slog.Logger, userID uint64, password string) {
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		log.Error("failed to rehash password", sl.Err(err))
+		return
+	}
+
+	if err := a.userRepo.SetPasswordHash(ctx, userID, hash); err != nil {
+		log.Error("failed to store rehashed password", sl.Err(err))
+		return
+	}
+
+	log.Info("password rehashed", slog.String("alg", a.hasher.Alg()))
+}
+
+// startSession creates a session with its first refresh token.
+func (a *Auth) startSession(ctx context.Context, usr user.User, device user.Device) (user.TokenPair, error) {
+	refreshToken, refreshHash, err := user.NewOpaqueToken()
+	if err != nil {
+		return user.TokenPair{}, err
+	}
+
+	sessionID, err := a.tokenRepo.CreateSession(ctx, usr.ID, device, refreshHash, a.refreshTTL)
+	if err != nil {
+		return user.TokenPair{}, err
+	}
+
+	return a.issue(usr, sessionID, refreshToken)
+}
+
+// issue signs an access token to go with the refresh token.
+func (a *Auth) issue(usr user.User, sessionID uint64, refreshToken string) (user.TokenPair, error) {
+	accessToken, err := jwt.NewToken(usr, sessionID, a.keyring, a.tokenTTL)
+	if err != nil {
+		return user.TokenPair{}, err
 	}
 
-	return token, nil
+	return user.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(a.tokenTTL),
+	}, nil
 }
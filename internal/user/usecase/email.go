@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/notifier"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/user"
+	"messanger/internal/user/repository"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrInvalidEmailToken = errors.New("invalid or expired verification token")
+	ErrEmailTaken        = errors.New("email is taken")
+)
+
+type EmailUC interface {
+	// RequestVerification sends a verification token to the address, it
+	// becomes the user's email once Verify accepts the token. It takes the
+	// current password, as the email receives password resets.
+	RequestVerification(ctx context.Context, userID uint64, email, currentPassword string) error
+	// Verify sets the email the token was sent to.
+	Verify(ctx context.Context, token string) error
+}
+
+type Email struct {
+	log       *slog.Logger
+	userRepo  repository.UserRepo
+	emailRepo repository.EmailTokenRepo
+	notifier  notifier.Notifier
+	hasher    *passwd.Hasher
+	verifyTTL time.Duration
+	verifyURL string
+}
+
+// NewEmail makes the email usecase. verifyURL is the client page that takes
+// the token as ?token=, the bare token is sent when it's empty.
+func NewEmail(
+	log *slog.Logger,
+	userRepo repository.UserRepo,
+	emailRepo repository.EmailTokenRepo,
+	notifier notifier.Notifier,
+	hasher *passwd.Hasher,
+	verifyTTL time.Duration,
+	verifyURL string,
+) *Email {
+	return &Email{
+		log:       log,
+		userRepo:  userRepo,
+		emailRepo: emailRepo,
+		notifier:  notifier,
+		hasher:    hasher,
+		verifyTTL: verifyTTL,
+		verifyURL: verifyURL,
+	}
+}
+
+func (e *Email) RequestVerification(ctx context.Context, userID uint64, email, currentPassword string) error {
+	const op = "user.usecase.email.RequestVerification"
+
+	log := e.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	hash, err := e.userRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := e.hasher.Verify(hash, currentPassword); err != nil {
+		log.Info("invalid credentials", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	token, tokenHash, err := user.NewOpaqueToken()
+	if err != nil {
+		log.Error("failed to generate verification token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := e.emailRepo.CreateEmailToken(ctx, userID, email, tokenHash, e.verifyTTL); err != nil {
+		log.Error("verification token creation error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg := notifier.Message{
+		To:      email,
+		Subject: "Email verification",
+		Body:    e.verifyBody(token),
+	}
+
+	if err := e.notifier.Send(ctx, msg); err != nil {
+		log.Error("failed to send verification token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (e *Email) Verify(ctx context.Context, token string) error {
+	const op = "user.usecase.email.Verify"
+
+	log := e.log.With(
+		slog.String("op", op),
+	)
+
+	userID, err := e.emailRepo.ConsumeEmailToken(ctx, user.HashToken(token))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrVerifyNotFound):
+			log.Info("invalid verification token")
+			return fmt.Errorf("%s: %w", op, ErrInvalidEmailToken)
+		case errors.Is(err, repository.ErrEmailTaken):
+			log.Warn("email is taken")
+			return fmt.Errorf("%s: %w", op, ErrEmailTaken)
+		}
+
+		log.Error("failed to consume verification token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email verified", slog.Uint64("user_id", userID))
+
+	return nil
+}
+
+func (e *Email) verifyBody(token string) string {
+	link := token
+	if e.verifyURL != "" {
+		link = e.verifyURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return fmt.Sprintf(
+		"Someone has asked to use this address for their account.\n\n"+
+			"Use the following to confirm it within %s:\n\n%s\n\n"+
+			"If it wasn't you, ignore this message.\n",
+		e.verifyTTL, link,
+	)
+}
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/lib/totp"
+	"messanger/internal/user"
+	"messanger/internal/user/repository"
+	"time"
+)
+
+var (
+	ErrInvalidCode     = errors.New("invalid code")
+	ErrInvalidMFAToken = errors.New("invalid or expired mfa token")
+	ErrMFANotEnrolled  = errors.New("2fa enrollment has not been started")
+	ErrMFAEnabled      = errors.New("2fa is already enabled")
+	ErrMFADisabled     = errors.New("2fa is not enabled")
+)
+
+const (
+	totpIssuer = "messanger"
+	// totpSkew is how many steps around the current one are accepted, for
+	// clocks that drift.
+	totpSkew = 1
+
+	mfaChallengeTTL = time.Minute * 5
+	mfaMaxAttempts  = 5
+)
+
+type MFAUC interface {
+	// Enroll starts setting up TOTP. The secret is enabled once Confirm
+	// accepts a code of it, enrolling again replaces a pending secret.
+	Enroll(ctx context.Context, userID uint64) (user.TOTPEnrollment, error)
+	// Confirm enables 2FA and returns the recovery codes, they are shown only
+	// once.
+	Confirm(ctx context.Context, userID uint64, code string) ([]string, error)
+	// Disable turns 2FA off, it takes the password and a code.
+	Disable(ctx context.Context, userID uint64, password, code string) error
+}
+
+type MFA struct {
+	log      *slog.Logger
+	userRepo repository.UserRepo
+	mfaRepo  repository.MFARepo
+	hasher   *passwd.Hasher
+}
+
+func NewMFA(log *slog.Logger, userRepo repository.UserRepo, mfaRepo repository.MFARepo, hasher *passwd.Hasher) *MFA {
+	return &MFA{
+		log:      log,
+		userRepo: userRepo,
+		mfaRepo:  mfaRepo,
+		hasher:   hasher,
+	}
+}
+
+func (m *MFA) Enroll(ctx context.Context, userID uint64) (user.TOTPEnrollment, error) {
+	const op = "user.usecase.mfa.Enroll"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	current, err := m.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrTOTPNotFound) {
+		log.Error("failed to get totp", sl.Err(err))
+		return user.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if current.Enabled() {
+		return user.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, ErrMFAEnabled)
+	}
+
+	usr, err := m.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return user.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+		return user.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.mfaRepo.SaveTOTP(ctx, userID, secret); err != nil {
+		if errors.Is(err, repository.ErrTOTPEnabled) {
+			return user.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, ErrMFAEnabled)
+		}
+
+		log.Error("failed to save totp", sl.Err(err))
+		return user.TOTPEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, usr.Login, secret),
+	}, nil
+}
+
+func (m *MFA) Confirm(ctx context.Context, userID uint64, code string) ([]string, error) {
+	const op = "user.usecase.mfa.Confirm"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	current, err := m.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnrolled)
+		}
+
+		log.Error("failed to get totp", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if current.Enabled() {
+		return nil, fmt.Errorf("%s: %w", op, ErrMFAEnabled)
+	}
+
+	step, ok := totp.Validate(current.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	codes, hashes, err := user.NewRecoveryCodes()
+	if err != nil {
+		log.Error("failed to generate recovery codes", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.mfaRepo.ConfirmTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnrolled)
+		}
+
+		log.Error("failed to confirm totp", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("2fa enabled")
+
+	return codes, nil
+}
+
+func (m *MFA) Disable(ctx context.Context, userID uint64, password, code string) error {
+	const op = "user.usecase.mfa.Disable"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	hash, err := m.userRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := m.hasher.Verify(hash, password); err != nil {
+		log.Info("invalid credentials", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	current, err := m.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrTOTPNotFound) {
+		log.Error("failed to get totp", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !current.Enabled() {
+		return fmt.Errorf("%s: %w", op, ErrMFADisabled)
+	}
+
+	if err := verifyCode(ctx, m.mfaRepo, current, code); err != nil {
+		log.Info("code rejected", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.mfaRepo.DeleteTOTP(ctx, userID); err != nil && !errors.Is(err, repository.ErrTOTPNotFound) {
+		log.Error("failed to delete totp", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("2fa disabled")
+
+	return nil
+}
+
+// verifyCode accepts either a TOTP code or one of the recovery codes, each
+// only once.
+func verifyCode(ctx context.Context, repo repository.MFARepo, current user.TOTP, code string) error {
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(current.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return ErrInvalidCode
+		}
+
+		if err := repo.UseTOTPStep(ctx, current.UserID, step); err != nil {
+			if errors.Is(err, repository.ErrCodeReplayed) {
+				return ErrInvalidCode
+			}
+
+			return err
+		}
+
+		return nil
+	}
+
+	if err := repo.UseRecoveryCode(ctx, current.UserID, user.HashRecoveryCode(code)); err != nil {
+		if errors.Is(err, repository.ErrRecoveryNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/notifier"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/user"
+	"messanger/internal/user/repository"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+type PasswordUC interface {
+	// Change sets a new password after checking the old one and ends all the
+	// user's sessions but the current one.
+	Change(ctx context.Context, userID uint64, sessionID uint64, oldPassword, newPassword string) error
+	// RequestReset sends a reset token to the user's verified email. Unknown
+	// logins and accounts without one are ignored, so the caller can't tell
+	// which logins exist.
+	RequestReset(ctx context.Context, login string) error
+	// Reset sets a new password with a reset token and ends all the user's
+	// sessions.
+	Reset(ctx context.Context, token string, newPassword string) error
+}
+
+type Password struct {
+	log       *slog.Logger
+	userRepo  repository.UserRepo
+	resetRepo repository.ResetTokenRepo
+	sessions  SessionsUC
+	notifier  notifier.Notifier
+	hasher    *passwd.Hasher
+	policy    passwd.Policy
+	resetTTL  time.Duration
+	resetURL  string
+}
+
+// NewPassword makes the password usecase. resetURL is the client page that
+// takes the token as ?token=, the bare token is sent when it's empty.
+func NewPassword(
+	log *slog.Logger,
+	userRepo repository.UserRepo,
+	resetRepo repository.ResetTokenRepo,
+	sessions SessionsUC,
+	notifier notifier.Notifier,
+	hasher *passwd.Hasher,
+	policy passwd.Policy,
+	resetTTL time.Duration,
+	resetURL string,
+) *Password {
+	return &Password{
+		log:       log,
+		userRepo:  userRepo,
+		resetRepo: resetRepo,
+		sessions:  sessions,
+		notifier:  notifier,
+		hasher:    hasher,
+		policy:    policy,
+		resetTTL:  resetTTL,
+		resetURL:  resetURL,
+	}
+}
+
+func (p *Password) Change(ctx context.Context, userID uint64, sessionID uint64, oldPassword, newPassword string) error {
+	const op = "user.usecase.password.Change"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	if err := p.policy.Validate(newPassword); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	hash, err := p.userRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := p.hasher.Verify(hash, oldPassword); err != nil {
+		log.Info("invalid credentials", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if err := p.setPassword(ctx, userID, newPassword); err != nil {
+		log.Error("failed to set password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := p.sessions.RevokeOthers(ctx, userID, sessionID); err != nil {
+		log.Error("failed to revoke sessions", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Password) RequestReset(ctx context.Context, login string) error {
+	const op = "user.usecase.password.RequestReset"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.String("login", login),
+	)
+
+	usr, err := p.userRepo.GetByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			log.Info("reset requested for unknown login")
+			return nil
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if usr.Deleted() {
+		log.Info("reset requested for deleted account")
+		return nil
+	}
+
+	if usr.Email == "" {
+		log.Info("reset requested for account without verified email")
+		return nil
+	}
+
+	token, hash, err := user.NewOpaqueToken()
+	if err != nil {
+		log.Error("failed to generate reset token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := p.resetRepo.CreateResetToken(ctx, usr.ID, hash, p.resetTTL); err != nil {
+		log.Error("reset token creation error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg := notifier.Message{
+		To:      usr.Email,
+		Subject: "Password reset",
+		Body:    p.resetBody(token),
+	}
+
+	if err := p.notifier.Send(ctx, msg); err != nil {
+		log.Error("failed to send reset token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Password) Reset(ctx context.Context, token string, newPassword string) error {
+	const op = "user.usecase.password.Reset"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	// Checked first so that a weak password doesn't burn the token.
+	if err := p.policy.Validate(newPassword); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	userID, err := p.resetRepo.ConsumeResetToken(ctx, user.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrResetNotFound) {
+			log.Info("invalid reset token")
+			return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+
+		log.Error("failed to consume reset token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Uint64("user_id", userID))
+
+	if err := p.setPassword(ctx, userID, newPassword); err != nil {
+		log.Error("failed to set password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Whoever knew the old password may still be logged in.
+	if _, err := p.sessions.RevokeOthers(ctx, userID, 0); err != nil {
+		log.Error("failed to revoke sessions", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Password) setPassword(ctx context.Context, userID uint64, password string) error {
+	hash, err := p.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return p.userRepo.SetPasswordHash(ctx, userID, hash)
+}
+
+func (p *Password) resetBody(token string) string {
+	link := token
+	if p.resetURL != "" {
+		link = p.resetURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return fmt.Sprintf(
+		"Someone has requested a password reset for your account.\n\n"+
+			"Use the following to set a new password within %s:\n\n%s\n\n"+
+			"If it wasn't you, ignore this message.\n",
+		p.resetTTL, link,
+	)
+}
@@ -0,0 +1,392 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"messanger/internal/lib/notifier"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/user"
+	"messanger/internal/user/repository"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	resetTTL    = time.Hour
+	resetURL    = "https://app.example.com/reset"
+	oldPassword = "old password"
+)
+
+// fakeUserRepo keeps users by login, the methods the password usecase
+// doesn't call aren't implemented.
+type fakeUserRepo struct {
+	repository.UserRepo
+
+	users  map[string]user.User
+	hashes map[uint64][]byte
+}
+
+func (f *fakeUserRepo) GetByLogin(_ context.Context, login string) (user.User, error) {
+	usr, ok := f.users[login]
+	if !ok {
+		return user.User{}, repository.ErrUserNotFound
+	}
+
+	usr.PasswordHash = f.hashes[usr.ID]
+
+	return usr, nil
+}
+
+func (f *fakeUserRepo) GetPasswordHash(_ context.Context, id uint64) ([]byte, error) {
+	hash, ok := f.hashes[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+
+	return hash, nil
+}
+
+func (f *fakeUserRepo) SetPasswordHash(_ context.Context, id uint64, hash []byte) error {
+	f.hashes[id] = hash
+
+	return nil
+}
+
+type fakeResetToken struct {
+	userID    uint64
+	expiresAt time.Time
+	used      bool
+}
+
+// fakeResetRepo follows the contract of ResetTokenRepo on a clock the test
+// moves.
+type fakeResetRepo struct {
+	now    time.Time
+	tokens map[string]*fakeResetToken
+}
+
+func (f *fakeResetRepo) CreateResetToken(_ context.Context, userID uint64, tokenHash []byte, ttl time.Duration) error {
+	for _, token := range f.tokens {
+		if token.userID == userID {
+			token.used = true
+		}
+	}
+
+	f.tokens[string(tokenHash)] = &fakeResetToken{
+		userID:    userID,
+		expiresAt: f.now.Add(ttl),
+	}
+
+	return nil
+}
+
+func (f *fakeResetRepo) ConsumeResetToken(_ context.Context, tokenHash []byte) (uint64, error) {
+	token, ok := f.tokens[string(tokenHash)]
+	if !ok || token.used || !f.now.Before(token.expiresAt) {
+		return 0, repository.ErrResetNotFound
+	}
+
+	token.used = true
+
+	return token.userID, nil
+}
+
+type passwordEnv struct {
+	users    *fakeUserRepo
+	resets   *fakeResetRepo
+	sessions *Sessions
+	hasher   *passwd.Hasher
+	outbox   string
+	password *Password
+}
+
+// newPasswordEnv has alice with a verified email and two sessions, bob
+// without an email and carol, who has deleted her account. Messages go
+// through the file notifier to outbox.
+func newPasswordEnv(t *testing.T) *passwordEnv {
+	t.Helper()
+
+	log := slog.New(slog.DiscardHandler)
+
+	hasher, err := passwd.NewHasher(passwd.AlgBcrypt, bcrypt.MinCost, passwd.DefaultArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := hasher.Hash(oldPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deletedAt := time.Now()
+
+	users := &fakeUserRepo{
+		users: map[string]user.User{
+			"alice": {ID: 1, Login: "alice", Email: "alice@example.com"},
+			"bob":   {ID: 2, Login: "bob"},
+			"carol": {ID: 3, Login: "carol", Email: "carol@example.com", DeletedAt: &deletedAt},
+		},
+		hashes: map[uint64][]byte{1: hash, 2: hash, 3: hash},
+	}
+	resets := &fakeResetRepo{
+		now:    time.Now(),
+		tokens: make(map[string]*fakeResetToken),
+	}
+	sessions := NewSessions(log, &fakeSessionRepo{
+		owners: map[uint64]uint64{10: 1, 11: 1, 20: 2},
+	}, time.Hour)
+	outbox := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	return &passwordEnv{
+		users:    users,
+		resets:   resets,
+		sessions: sessions,
+		hasher:   hasher,
+		outbox:   outbox,
+		password: NewPassword(
+			log, users, resets, sessions, notifier.NewFile(log, outbox),
+			hasher, passwd.NewPolicy(8, passwd.AlgBcrypt), resetTTL, resetURL,
+		),
+	}
+}
+
+// sent reads the messages the file notifier has written.
+func (e *passwordEnv) sent(t *testing.T) []notifier.Message {
+	t.Helper()
+
+	file, err := os.Open(e.outbox)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var msgs []notifier.Message
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg notifier.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return msgs
+}
+
+var resetLink = regexp.MustCompile(regexp.QuoteMeta(resetURL) + `\?token=(\S+)`)
+
+// tokens returns the reset tokens sent so far, oldest first.
+func (e *passwordEnv) tokens(t *testing.T) []string {
+	t.Helper()
+
+	var tokens []string
+
+	for _, msg := range e.sent(t) {
+		m := resetLink.FindStringSubmatch(msg.Body)
+		if m == nil {
+			t.Fatalf("no reset link in %q", msg.Body)
+		}
+
+		token, err := url.QueryUnescape(m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+func TestRequestReset(t *testing.T) {
+	tests := []struct {
+		name   string
+		login  string
+		wantTo string
+	}{
+		{"verified email", "alice", "alice@example.com"},
+		{"no verified email", "bob", ""},
+		{"deleted account", "carol", ""},
+		{"unknown login", "dave", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newPasswordEnv(t)
+
+			// Unknown and unreachable accounts look the same to the caller.
+			if err := env.password.RequestReset(context.Background(), tt.login); err != nil {
+				t.Fatalf("RequestReset: %v", err)
+			}
+
+			msgs := env.sent(t)
+
+			if tt.wantTo == "" {
+				if len(msgs) != 0 {
+					t.Fatalf("sent %d messages, want none", len(msgs))
+				}
+				return
+			}
+
+			if len(msgs) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(msgs))
+			}
+			if msgs[0].To != tt.wantTo {
+				t.Errorf("sent to %q, want %q", msgs[0].To, tt.wantTo)
+			}
+			if len(env.tokens(t)) != 1 {
+				t.Error("message has no reset token")
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests int
+		// token is the index of the sent token to use, -1 for an unknown one.
+		token    int
+		advance  time.Duration
+		attempts []string
+		wantErrs []error
+		// wantPassword is the password alice ends up with.
+		wantPassword string
+	}{
+		{
+			name:         "valid token",
+			requests:     1,
+			token:        0,
+			attempts:     []string{"new password"},
+			wantErrs:     []error{nil},
+			wantPassword: "new password",
+		},
+		{
+			name:         "single use",
+			requests:     1,
+			token:        0,
+			attempts:     []string{"new password", "newer password"},
+			wantErrs:     []error{nil, ErrInvalidResetToken},
+			wantPassword: "new password",
+		},
+		{
+			name:         "expired",
+			requests:     1,
+			token:        0,
+			advance:      resetTTL + time.Second,
+			attempts:     []string{"new password"},
+			wantErrs:     []error{ErrInvalidResetToken},
+			wantPassword: oldPassword,
+		},
+		{
+			name:         "just before expiry",
+			requests:     1,
+			token:        0,
+			advance:      resetTTL - time.Second,
+			attempts:     []string{"new password"},
+			wantErrs:     []error{nil},
+			wantPassword: "new password",
+		},
+		{
+			name:         "older token invalidated",
+			requests:     2,
+			token:        0,
+			attempts:     []string{"new password"},
+			wantErrs:     []error{ErrInvalidResetToken},
+			wantPassword: oldPassword,
+		},
+		{
+			name:         "newest token",
+			requests:     2,
+			token:        1,
+			attempts:     []string{"new password"},
+			wantErrs:     []error{nil},
+			wantPassword: "new password",
+		},
+		{
+			name:         "weak password keeps the token",
+			requests:     1,
+			token:        0,
+			attempts:     []string{"short", "new password"},
+			wantErrs:     []error{passwd.ErrTooShort, nil},
+			wantPassword: "new password",
+		},
+		{
+			name:         "unknown token",
+			requests:     1,
+			token:        -1,
+			attempts:     []string{"new password"},
+			wantErrs:     []error{ErrInvalidResetToken},
+			wantPassword: oldPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newPasswordEnv(t)
+
+			for range tt.requests {
+				if err := env.password.RequestReset(ctx, "alice"); err != nil {
+					t.Fatalf("RequestReset: %v", err)
+				}
+			}
+
+			tokens := env.tokens(t)
+			if len(tokens) != tt.requests {
+				t.Fatalf("sent %d tokens, want %d", len(tokens), tt.requests)
+			}
+
+			token := "unknown"
+			if tt.token >= 0 {
+				token = tokens[tt.token]
+			}
+
+			env.resets.now = env.resets.now.Add(tt.advance)
+
+			for i, password := range tt.attempts {
+				err := env.password.Reset(ctx, token, password)
+				if !errors.Is(err, tt.wantErrs[i]) {
+					t.Fatalf("attempt %d: err = %v, want %v", i+1, err, tt.wantErrs[i])
+				}
+			}
+
+			if _, err := env.hasher.Verify(env.users.hashes[1], tt.wantPassword); err != nil {
+				t.Errorf("password isn't %q: %v", tt.wantPassword, err)
+			}
+
+			// A reset ends every session of the user and nobody else's.
+			reset := tt.wantPassword != oldPassword
+
+			for _, sessionID := range []uint64{10, 11} {
+				active, err := env.sessions.Active(ctx, 1, sessionID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if active == reset {
+					t.Errorf("session %d active = %v after reset = %v", sessionID, active, reset)
+				}
+			}
+
+			if active, _ := env.sessions.Active(ctx, 2, 20); !active {
+				t.Error("another user's session was revoked")
+			}
+		})
+	}
+}
@@ -5,26 +5,118 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/lib/passwd"
+	"messanger/internal/user"
 	"messanger/internal/user/repository"
+	"time"
 )
 
+var (
+	ErrPasswordRequired = errors.New("current password is required to change login")
+	ErrAccountDeleted   = errors.New("account is deleted")
+	ErrNotDeleted       = errors.New("account is not deleted")
+)
+
+const purgePeriod = time.Hour
+
 type ProfileUC interface {
-	Delete(context.Context, uint64) error
+	Get(ctx context.Context, id uint64) (user.User, error)
+	// Update applies a partial update of the profile. Changing the login
+	// needs the current password.
+	Update(ctx context.Context, id uint64, upd user.ProfileUpdate, currentPassword string) (user.User, error)
+	// Delete ends the user's sessions and schedules the account to be
+	// purged, it returns when. Until then the account can be restored.
+	Delete(ctx context.Context, id uint64) (time.Time, error)
+	// Restore brings back a deleted account that hasn't been purged yet, it
+	// takes the login and password as the user has no session.
+	Restore(ctx context.Context, login, password string) error
 }
 
 type Profile struct {
 	log      *slog.Logger
 	userRepo repository.UserRepo
+	sessions SessionsUC
+	hasher   *passwd.Hasher
+	grace    time.Duration
 }
 
-func NewProfile(log *slog.Logger, userRepo repository.UserRepo) *Profile {
+// NewProfile makes the profile usecase, a deleted account is purged after
+// the grace period.
+func NewProfile(
+	log *slog.Logger,
+	userRepo repository.UserRepo,
+	sessions SessionsUC,
+	hasher *passwd.Hasher,
+	grace time.Duration,
+) *Profile {
 	return &Profile{
 		log:      log,
 		userRepo: userRepo,
+		sessions: sessions,
+		hasher:   hasher,
+		grace:    grace,
+	}
+}
+
+func (a *Profile) Get(ctx context.Context, id uint64) (user.User, error) {
+	const op = "user.usecase.profile.Get"
+
+	usr, err := a.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return user.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if usr.Deleted() {
+		return user.User{}, fmt.Errorf("%s: %w", op, repository.ErrUserNotFound)
+	}
+
+	return usr, nil
+}
+
+func (a *Profile) Update(ctx context.Context, id uint64, upd user.ProfileUpdate, currentPassword string) (user.User, error) {
+	const op = "user.usecase.profile.Update"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Uint64("id", id),
+	)
+
+	if upd.IsEmpty() {
+		return a.Get(ctx, id)
+	}
+
+	if upd.Login != nil {
+		if currentPassword == "" {
+			return user.User{}, fmt.Errorf("%s: %w", op, ErrPasswordRequired)
+		}
+
+		hash, err := a.userRepo.GetPasswordHash(ctx, id)
+		if err != nil {
+			return user.User{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if _, err := a.hasher.Verify(hash, currentPassword); err != nil {
+			log.Info("invalid credentials", sl.Err(err))
+			return user.User{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
 	}
+
+	usr, err := a.userRepo.Update(ctx, id, upd)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserAlreadyExist) {
+			log.Warn("login is taken")
+			return user.User{}, fmt.Errorf("%s: %w", op, repository.ErrUserAlreadyExist)
+		}
+
+		log.Error("profile update error", sl.Err(err))
+		return user.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return usr, nil
 }
 
-func (a *Profile) Delete(ctx context.Context, id uint64) error {
+func (a *Profile) Delete(ctx context.Context, id uint64) (time.Time, error) {
 	const op = "user.usecase.profile.Delete"
 
 	log := a.log.With(
@@ -32,15 +124,93 @@ func (a *Profile) Delete(ctx context.Context, id uint64) error {
 		slog.Uint64("id", id),
 	)
 
-	if err := a.userRepo.Delete(ctx, id); err != nil {
+	purgeAt, err := a.userRepo.Delete(ctx, id, a.grace)
+	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			log.Warn("user not found")
 
-			return fmt.Errorf("%s: %w", op, repository.ErrUserNotFound)
+			return time.Time{}, fmt.Errorf("%s: %w", op, repository.ErrUserNotFound)
+		}
+
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.sessions.RevokeOthers(ctx, id, 0); err != nil {
+		log.Error("failed to revoke sessions", sl.Err(err))
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("account deleted", slog.Time("purge_at", purgeAt))
+
+	return purgeAt, nil
+}
+
+func (a *Profile) Restore(ctx context.Context, login, password string) error {
+	const op = "user.usecase.profile.Restore"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("login", login),
+	)
+
+	usr, err := a.userRepo.GetByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.hasher.Verify(usr.PasswordHash, password); err != nil {
+		log.Info("invalid credentials", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if !usr.Deleted() {
+		return fmt.Errorf("%s: %w", op, ErrNotDeleted)
+	}
+
+	if err := a.userRepo.Restore(ctx, usr.ID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrNotDeleted)
 		}
 
+		log.Error("failed to restore account", sl.Err(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	log.Info("account restored")
+
 	return nil
 }
+
+// RunPurge periodically anonymizes the accounts whose grace period is over
+// until ctx is cancelled.
+func (a *Profile) RunPurge(ctx context.Context) {
+	const op = "user.usecase.profile.RunPurge"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	ticker := time.NewTicker(purgePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ids, err := a.userRepo.PurgeDeleted(ctx)
+			if err != nil {
+				log.Error("failed to purge deleted accounts", sl.Err(err))
+				continue
+			}
+
+			for _, id := range ids {
+				log.Info("account purged", slog.Uint64("id", id))
+			}
+		}
+	}
+}
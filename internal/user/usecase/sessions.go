@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"messanger/internal/lib/logger/sl"
+	"messanger/internal/user"
+	"messanger/internal/user/repository"
+	"sync"
+	"time"
+)
+
+const sessionCacheSize = 10000
+
+type SessionsUC interface {
+	List(ctx context.Context, userID uint64) ([]user.Session, error)
+	Revoke(ctx context.Context, userID uint64, sessionID uint64) error
+	// RevokeByToken ends the session the refresh token belongs to, on
+	// logout.
+	RevokeByToken(ctx context.Context, refreshToken string) error
+	// RevokeOthers ends every session of the user but the current one and
+	// returns how many have been revoked.
+	RevokeOthers(ctx context.Context, userID uint64, currentID uint64) (int, error)
+	// Active reports whether an access token of the session may still be
+	// used. Answers are cached, so a session revoked on another replica is
+	// rejected after the cache TTL at most.
+	Active(ctx context.Context, userID uint64, sessionID uint64) (bool, error)
+}
+
+type sessionEntry struct {
+	userID uint64
+	active bool
+	until  time.Time
+}
+
+type Sessions struct {
+	log         *slog.Logger
+	sessionRepo repository.SessionRepo
+	cacheTTL    time.Duration
+
+	mu    sync.Mutex
+	cache map[uint64]sessionEntry
+}
+
+func NewSessions(log *slog.Logger, sessionRepo repository.SessionRepo, cacheTTL time.Duration) *Sessions {
+	return &Sessions{
+		log:         log,
+		sessionRepo: sessionRepo,
+		cacheTTL:    cacheTTL,
+		cache:       make(map[uint64]sessionEntry),
+	}
+}
+
+func (s *Sessions) List(ctx context.Context, userID uint64) ([]user.Session, error) {
+	const op = "user.usecase.sessions.List"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+	)
+
+	sessions, err := s.sessionRepo.ListSessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to list sessions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+func (s *Sessions) Revoke(ctx context.Context, userID uint64, sessionID uint64) error {
+	const op = "user.usecase.sessions.Revoke"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("session_id", sessionID),
+	)
+
+	if err := s.sessionRepo.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return fmt.Errorf("%s: %w", op, repository.ErrSessionNotFound)
+		}
+
+		log.Error("session revocation error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.forget(sessionID)
+
+	return nil
+}
+
+func (s *Sessions) RevokeByToken(ctx context.Context, refreshToken string) error {
+	const op = "user.usecase.sessions.RevokeByToken"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	sessionID, err := s.sessionRepo.RevokeSessionByToken(ctx, user.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("session revocation error", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.forget(sessionID)
+
+	return nil
+}
+
+func (s *Sessions) RevokeOthers(ctx context.Context, userID uint64, currentID uint64) (int, error) {
+	const op = "user.usecase.sessions.RevokeOthers"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Uint64("user_id", userID),
+		slog.Uint64("session_id", currentID),
+	)
+
+	revoked, err := s.sessionRepo.RevokeOtherSessions(ctx, userID, currentID)
+	if err != nil {
+		log.Error("session revocation error", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.forget(revoked...)
+
+	return len(revoked), nil
+}
+
+func (s *Sessions) Active(ctx context.Context, userID uint64, sessionID uint64) (bool, error) {
+	const op = "user.usecase.sessions.Active"
+
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[sessionID]
+	s.mu.Unlock()
+
+	if ok && now.Before(entry.until) {
+		return entry.active && entry.userID == userID, nil
+	}
+
+	// A cache miss also refreshes the session's last-seen time, so it's
+	// updated once per TTL at most.
+	ownerID, err := s.sessionRepo.TouchSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		s.log.Error("failed to check session", slog.String("op", op), sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	entry = sessionEntry{
+		userID: ownerID,
+		active: err == nil,
+		until:  now.Add(s.cacheTTL),
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= sessionCacheSize {
+		s.evict(now)
+	}
+	s.cache[sessionID] = entry
+	s.mu.Unlock()
+
+	return entry.active && entry.userID == userID, nil
+}
+
+// forget drops cached answers for revoked sessions.
+func (s *Sessions) forget(sessionIDs ...uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range sessionIDs {
+		delete(s.cache, id)
+	}
+}
+
+// evict drops expired entries, or the whole cache if none has expired.
+// Must be called with mu held.
+func (s *Sessions) evict(now time.Time) {
+	for id, entry := range s.cache {
+		if !now.Before(entry.until) {
+			delete(s.cache, id)
+		}
+	}
+
+	if len(s.cache) >= sessionCacheSize {
+		clear(s.cache)
+	}
+}
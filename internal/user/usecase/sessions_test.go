@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"messanger/internal/user"
+	"messanger/internal/user/repository"
+	"testing"
+	"time"
+)
+
+// fakeSessionRepo keeps the owners of active sessions and counts the
+// lookups Active makes.
+type fakeSessionRepo struct {
+	owners  map[uint64]uint64
+	tokens  map[string]uint64
+	touches int
+	err     error
+}
+
+func (f *fakeSessionRepo) TouchSession(_ context.Context, sessionID uint64) (uint64, error) {
+	f.touches++
+
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	owner, ok := f.owners[sessionID]
+	if !ok {
+		return 0, repository.ErrSessionNotFound
+	}
+
+	return owner, nil
+}
+
+func (f *fakeSessionRepo) ListSessions(context.Context, uint64) ([]user.Session, error) {
+	return nil, nil
+}
+
+func (f *fakeSessionRepo) RevokeSession(_ context.Context, userID uint64, sessionID uint64) error {
+	if f.owners[sessionID] != userID {
+		return repository.ErrSessionNotFound
+	}
+
+	delete(f.owners, sessionID)
+
+	return nil
+}
+
+func (f *fakeSessionRepo) RevokeSessionByToken(_ context.Context, tokenHash []byte) (uint64, error) {
+	sessionID, ok := f.tokens[string(tokenHash)]
+	if _, active := f.owners[sessionID]; !ok || !active {
+		return 0, repository.ErrTokenNotFound
+	}
+
+	delete(f.owners, sessionID)
+
+	return sessionID, nil
+}
+
+func (f *fakeSessionRepo) RevokeOtherSessions(_ context.Context, userID uint64, keepID uint64) ([]uint64, error) {
+	var revoked []uint64
+
+	for id, owner := range f.owners {
+		if owner == userID && id != keepID {
+			delete(f.owners, id)
+			revoked = append(revoked, id)
+		}
+	}
+
+	return revoked, nil
+}
+
+func TestSessionsActive(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		err         error
+		userID      uint64
+		sessionID   uint64
+		calls       int
+		want        bool
+		wantErr     error
+		wantTouches int
+	}{
+		{"active is cached", time.Minute, nil, 1, 10, 3, true, nil, 1},
+		{"missing is cached", time.Minute, nil, 1, 99, 3, false, nil, 1},
+		{"other user's session", time.Minute, nil, 2, 10, 3, false, nil, 1},
+		{"expired entries are looked up again", 0, nil, 1, 10, 3, true, nil, 3},
+		{"errors aren't cached", time.Minute, errDB, 1, 10, 3, false, errDB, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSessionRepo{
+				owners: map[uint64]uint64{10: 1},
+				err:    tt.err,
+			}
+			sessions := NewSessions(slog.New(slog.DiscardHandler), repo, tt.ttl)
+
+			for i := range tt.calls {
+				got, err := sessions.Active(context.Background(), tt.userID, tt.sessionID)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("call %d: err = %v, want %v", i+1, err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("call %d: Active = %v, want %v", i+1, got, tt.want)
+				}
+			}
+
+			if repo.touches != tt.wantTouches {
+				t.Errorf("%d lookups, want %d", repo.touches, tt.wantTouches)
+			}
+		})
+	}
+}
+
+func TestSessionsRevokeForgets(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &fakeSessionRepo{
+		owners: map[uint64]uint64{10: 1, 11: 1, 12: 1, 13: 1, 20: 2},
+		tokens: map[string]uint64{string(user.HashToken("refresh")): 13},
+	}
+	sessions := NewSessions(slog.New(slog.DiscardHandler), repo, time.Hour)
+
+	for _, id := range []uint64{10, 11, 12, 13} {
+		if ok, _ := sessions.Active(ctx, 1, id); !ok {
+			t.Fatalf("session %d isn't active", id)
+		}
+	}
+	if ok, _ := sessions.Active(ctx, 2, 20); !ok {
+		t.Fatal("session 20 isn't active")
+	}
+
+	if err := sessions.Revoke(ctx, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.RevokeByToken(ctx, "refresh"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.RevokeByToken(ctx, "refresh"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("second RevokeByToken err = %v, want %v", err, ErrInvalidToken)
+	}
+	if n, err := sessions.RevokeOthers(ctx, 1, 11); err != nil || n != 1 {
+		t.Fatalf("RevokeOthers = (%d, %v), want (1, nil)", n, err)
+	}
+
+	tests := []struct {
+		userID    uint64
+		sessionID uint64
+		want      bool
+	}{
+		{1, 10, false},
+		{1, 11, true},
+		{1, 12, false},
+		{1, 13, false},
+		{2, 20, true},
+	}
+
+	for _, tt := range tests {
+		got, err := sessions.Active(ctx, tt.userID, tt.sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Active(%d) after revocation = %v, want %v", tt.sessionID, got, tt.want)
+		}
+	}
+}
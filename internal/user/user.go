@@ -2,12 +2,46 @@ package user
 
 import "time"
 
+// DeletedName replaces the name of a purged account.
+const DeletedName = "Deleted account"
+
+// DeletedLoginPrefix starts the placeholder login of a purged account, the
+// prefix is reserved so that no one can take a placeholder before a purge.
+const DeletedLoginPrefix = "deleted-"
+
 type User struct {
-	ID           uint64
-	Name         string
-	Login        string
+	ID    uint64
+	Name  string
+	Login string
+	// Email is the verified address password resets are sent to, it's empty
+	// until the user verifies one.
+	Email string
+	Bio   string
+	// Avatar is a reference to the image, the user doesn't store the image.
+	Avatar       string
 	PasswordHash []byte
 	CreatedAt    time.Time
+	// DeletedAt is set while the account waits to be purged at PurgeAt, it
+	// can be restored until then.
+	DeletedAt *time.Time
+	PurgeAt   *time.Time
+}
+
+func (u User) Deleted() bool {
+	return u.DeletedAt != nil
+}
+
+// ProfileUpdate is a partial update of a profile, nil fields are left as
+// they are.
+type ProfileUpdate struct {
+	Name   *string
+	Bio    *string
+	Avatar *string
+	Login  *string
+}
+
+func (u ProfileUpdate) IsEmpty() bool {
+	return u.Name == nil && u.Bio == nil && u.Avatar == nil && u.Login == nil
 }
 
 func NewUser(name, login string, passHash []byte) User {
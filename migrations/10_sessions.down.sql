@@ -0,0 +1,5 @@
+ALTER TABLE auth_sessions
+    DROP COLUMN IF EXISTS device_name,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip,
+    DROP COLUMN IF EXISTS last_seen_at;
@@ -0,0 +1,12 @@
+-- only sha256 of a reset token is stored, a token is used once
+CREATE TABLE password_reset_tokens(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    token_hash BYTEA NOT NULL UNIQUE,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP DEFAULT NULL
+);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
@@ -0,0 +1,40 @@
+-- a secret is pending until the user confirms it with a first code,
+-- last_step is the time step of the last accepted code so it can't be replayed
+CREATE TABLE user_totp(
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+
+    secret VARCHAR(64) NOT NULL,
+    last_step BIGINT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    confirmed_at TIMESTAMP DEFAULT NULL
+);
+
+-- only sha256 of a recovery code is stored, a code is used once
+CREATE TABLE recovery_codes(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    code_hash BYTEA NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    used_at TIMESTAMP DEFAULT NULL
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+-- a login with a correct password of a user with 2FA on issues a challenge,
+-- only sha256 of its token is stored
+CREATE TABLE mfa_challenges(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    token_hash BYTEA NOT NULL UNIQUE,
+    device_name VARCHAR(255) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    attempts INT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    expires_at TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_mfa_challenges_user_id ON mfa_challenges(user_id);
@@ -0,0 +1,8 @@
+-- attempts counted by the login and registration throttling, a key is the
+-- limiter name and an IP or a login
+CREATE TABLE auth_attempts(
+    key VARCHAR(512) PRIMARY KEY,
+    attempts INT NOT NULL DEFAULT 0,
+    last_attempt_at TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_auth_attempts_last_attempt_at ON auth_attempts(last_attempt_at);
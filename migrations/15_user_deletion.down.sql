@@ -0,0 +1,10 @@
+ALTER TABLE msgs
+    DROP CONSTRAINT msgs_author_user_id_fkey,
+    ADD CONSTRAINT msgs_author_user_id_fkey FOREIGN KEY (author_user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_users_purge_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS purge_at,
+    DROP COLUMN IF EXISTS purged_at;
@@ -0,0 +1,13 @@
+-- a deleted account can be restored until purge_at, then its personal data
+-- is anonymized and the row is kept so that its messages stay in the chats
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL,
+    ADD COLUMN purge_at TIMESTAMP DEFAULT NULL,
+    ADD COLUMN purged_at TIMESTAMP DEFAULT NULL;
+CREATE INDEX idx_users_purge_at ON users(purge_at) WHERE purged_at IS NULL;
+
+-- user rows are no longer deleted, a hard delete now fails instead of wiping
+-- the user's messages from every chat
+ALTER TABLE msgs
+    DROP CONSTRAINT msgs_author_user_id_fkey,
+    ADD CONSTRAINT msgs_author_user_id_fkey FOREIGN KEY (author_user_id) REFERENCES users(id);
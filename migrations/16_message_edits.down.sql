@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS msg_revisions CASCADE;
+
+ALTER TABLE msgs DROP COLUMN IF EXISTS edited_at;
@@ -0,0 +1,13 @@
+ALTER TABLE msgs ADD COLUMN edited_at TIMESTAMP DEFAULT NULL;
+
+-- the versions an edit replaced, created_at is when the version was written
+CREATE TABLE msg_revisions(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    msg_id BIGINT NOT NULL REFERENCES msgs(id) ON DELETE CASCADE,
+
+    text TEXT NOT NULL,
+
+    created_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL DEFAULT current_timestamp
+);
+CREATE INDEX idx_msg_revisions_msg_id_id ON msg_revisions(msg_id, id);
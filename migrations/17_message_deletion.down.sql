@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS msg_hidden CASCADE;
+
+ALTER TABLE msgs
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS deleted_by;
@@ -0,0 +1,15 @@
+-- a message deleted for everyone stays as a tombstone: the row and its id
+-- are kept for pagination and read markers, the text is gone
+ALTER TABLE msgs
+    ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL,
+    ADD COLUMN deleted_by BIGINT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL;
+
+-- messages a user deleted only for themselves
+CREATE TABLE msg_hidden(
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    msg_id BIGINT NOT NULL REFERENCES msgs(id) ON DELETE CASCADE,
+
+    hidden_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+
+    PRIMARY KEY (user_id, msg_id)
+);
@@ -0,0 +1,16 @@
+CREATE OR REPLACE FUNCTION chats_touch_last_msg() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE chats SET last_msg_id = NEW.id, last_activity_at = NEW.created_at
+    WHERE id = NEW.chat_id AND (last_msg_id IS NULL OR last_msg_id < NEW.id);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS thread_reads CASCADE;
+
+DROP INDEX IF EXISTS idx_msgs_thread_root_id_id;
+
+ALTER TABLE msgs
+    DROP COLUMN IF EXISTS thread_root_id,
+    DROP COLUMN IF EXISTS reply_to_msg_id;
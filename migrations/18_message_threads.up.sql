@@ -0,0 +1,32 @@
+-- reply_to_msg_id quotes a message, thread_root_id puts the message into the
+-- thread of a top-level one instead of the chat history
+ALTER TABLE msgs
+    ADD COLUMN reply_to_msg_id BIGINT DEFAULT NULL REFERENCES msgs(id) ON DELETE SET NULL,
+    ADD COLUMN thread_root_id BIGINT DEFAULT NULL REFERENCES msgs(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_msgs_thread_root_id_id ON msgs(thread_root_id, id) WHERE thread_root_id IS NOT NULL;
+
+-- per-thread read markers, chat_members.last_read_msg_id only covers the
+-- chat history
+CREATE TABLE thread_reads(
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    root_msg_id BIGINT NOT NULL REFERENCES msgs(id) ON DELETE CASCADE,
+
+    last_read_msg_id BIGINT NOT NULL,
+
+    PRIMARY KEY (user_id, root_msg_id)
+);
+
+-- thread replies are not the last message of the chat
+CREATE OR REPLACE FUNCTION chats_touch_last_msg() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.thread_root_id IS NOT NULL THEN
+        RETURN NULL;
+    END IF;
+
+    UPDATE chats SET last_msg_id = NEW.id, last_activity_at = NEW.created_at
+    WHERE id = NEW.chat_id AND (last_msg_id IS NULL OR last_msg_id < NEW.id);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
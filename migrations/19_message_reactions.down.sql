@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS msg_reactions CASCADE;
+
+ALTER TABLE chats DROP COLUMN IF EXISTS allowed_reactions;
@@ -0,0 +1,12 @@
+-- NULL allows any reaction, otherwise only the listed emoji are allowed
+ALTER TABLE chats ADD COLUMN allowed_reactions TEXT[] DEFAULT NULL;
+
+CREATE TABLE msg_reactions(
+    msg_id BIGINT NOT NULL REFERENCES msgs(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+
+    PRIMARY KEY (msg_id, user_id, emoji)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE msgs DROP COLUMN IF EXISTS service;
+
+DROP TABLE IF EXISTS chat_pins CASCADE;
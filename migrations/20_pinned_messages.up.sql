@@ -0,0 +1,14 @@
+CREATE TABLE chat_pins(
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    msg_id BIGINT NOT NULL REFERENCES msgs(id) ON DELETE CASCADE,
+
+    pinned_by BIGINT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    pinned_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+
+    PRIMARY KEY (chat_id, msg_id)
+);
+CREATE INDEX idx_chat_pins_chat_id_pinned_at ON chat_pins(chat_id, pinned_at);
+
+-- service messages tell the history about changes such as pins, their
+-- reply_to_msg_id is the message the change is about
+ALTER TABLE msgs ADD COLUMN service TEXT DEFAULT NULL;
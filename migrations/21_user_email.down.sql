@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS email_verification_tokens;
+
+DROP INDEX IF EXISTS idx_users_email;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email;
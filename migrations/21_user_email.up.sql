@@ -0,0 +1,20 @@
+-- a user's email is set only once it has been verified, password resets are
+-- sent there
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(255) DEFAULT NULL;
+CREATE UNIQUE INDEX idx_users_email ON users(lower(email));
+
+-- a verification token is sent to the new address, only its sha256 is
+-- stored and it's used once
+CREATE TABLE email_verification_tokens(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+
+    token_hash BYTEA NOT NULL UNIQUE,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP DEFAULT NULL
+);
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
@@ -0,0 +1,6 @@
+ALTER TABLE chat_moderation_log
+    ALTER COLUMN expires_at TYPE TIMESTAMP;
+
+ALTER TABLE chat_members
+    ALTER COLUMN banned_until TYPE TIMESTAMP,
+    ALTER COLUMN banned_at TYPE TIMESTAMP;
@@ -0,0 +1,9 @@
+-- ban expiries come from clients with their own offsets, TIMESTAMP dropped
+-- the offset and kept the wall clock. Existing values are read in the
+-- session's time zone, as now() wrote them.
+ALTER TABLE chat_members
+    ALTER COLUMN banned_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN banned_until TYPE TIMESTAMPTZ;
+
+ALTER TABLE chat_moderation_log
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS update_memberships;
+
+DROP INDEX IF EXISTS idx_updates_tx_id_id;
+
+ALTER TABLE updates
+    DROP COLUMN IF EXISTS tx_id;
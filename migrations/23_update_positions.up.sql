@@ -0,0 +1,22 @@
+-- ids are taken before commit and become visible out of order. Readers page
+-- by (tx_id, id) and only read transactions older than every running one,
+-- so nothing can show up behind a cursor. Existing rows get the migration's
+-- transaction.
+ALTER TABLE updates ADD COLUMN tx_id xid8 NOT NULL DEFAULT pg_current_xact_id();
+CREATE INDEX idx_updates_tx_id_id ON updates(tx_id, id);
+
+-- when a user could see the chat's updates, from the member.joined update
+-- to the member.left or member.banned one, both included
+CREATE TABLE update_memberships(
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+
+    from_id BIGINT NOT NULL,
+    until_id BIGINT DEFAULT NULL
+);
+CREATE INDEX idx_update_memberships_chat_id_user_id ON update_memberships(chat_id, user_id);
+CREATE UNIQUE INDEX idx_update_memberships_open ON update_memberships(chat_id, user_id) WHERE until_id IS NULL;
+
+-- current members keep seeing the log as before
+INSERT INTO update_memberships(chat_id, user_id, from_id)
+SELECT chat_id, user_id, 0 FROM chat_members WHERE NOT is_banned;
@@ -0,0 +1,8 @@
+-- events that are too large for a NOTIFY payload,
+-- listeners receive only the id and fetch the event from here
+CREATE TABLE bus_events(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_bus_events_created_at ON bus_events(created_at);
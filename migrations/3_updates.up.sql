@@ -0,0 +1,19 @@
+-- durable log of chat events that clients resume from with a cursor.
+-- chat_id has no foreign key on purpose: events of deleted chats
+-- (chat.deleted itself at least) must outlive the chat.
+-- recipient_user_id is set for events visible to a single user only.
+CREATE TABLE updates(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT DEFAULT NULL,
+    recipient_user_id BIGINT DEFAULT NULL,
+
+    type VARCHAR(64) NOT NULL,
+    payload JSONB DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_updates_chat_id_id ON updates(chat_id, id);
+CREATE INDEX idx_updates_user_id_id ON updates(user_id, id);
+CREATE INDEX idx_updates_recipient_user_id_id ON updates(recipient_user_id, id) WHERE recipient_user_id IS NOT NULL;
+CREATE INDEX idx_updates_created_at ON updates(created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE chat_members DROP CONSTRAINT IF EXISTS chat_members_role_check;
+ALTER TABLE chat_members ALTER COLUMN role SET DEFAULT 'user';
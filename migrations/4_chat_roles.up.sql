@@ -0,0 +1,7 @@
+-- owner > admin > moderator > member > subscriber(read-only, channels)
+UPDATE chat_members SET role = 'member'
+    WHERE role NOT IN ('owner', 'admin', 'moderator', 'member', 'subscriber');
+
+ALTER TABLE chat_members ALTER COLUMN role SET DEFAULT 'member';
+ALTER TABLE chat_members ADD CONSTRAINT chat_members_role_check
+    CHECK (role IN ('owner', 'admin', 'moderator', 'member', 'subscriber'));
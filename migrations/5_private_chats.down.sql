@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS private_chats CASCADE;
+
+-- chats_address_key stays a plain UNIQUE: NULLS NOT DISTINCT can't be
+-- restored once more than one chat has no address, which is the norm since
+-- private chats never have one.
@@ -0,0 +1,17 @@
+-- NULLS NOT DISTINCT allowed only one chat without an address,
+-- private chats never have one
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_address_key;
+ALTER TABLE chats ADD CONSTRAINT chats_address_key UNIQUE (address);
+
+UPDATE chats SET address = NULL WHERE type = 'private';
+
+-- one private chat per pair of users, the pair is stored ordered
+CREATE TABLE private_chats(
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(id) ON DELETE CASCADE,
+
+    user_low_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_high_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    UNIQUE (user_low_id, user_high_id),
+    CHECK (user_low_id < user_high_id)
+);
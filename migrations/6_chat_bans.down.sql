@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS msgs_reject_banned ON msgs;
+DROP FUNCTION IF EXISTS msgs_reject_banned();
+
+DROP TABLE IF EXISTS chat_moderation_log CASCADE;
+
+ALTER TABLE chat_members
+    DROP COLUMN IF EXISTS ban_reason,
+    DROP COLUMN IF EXISTS banned_by,
+    DROP COLUMN IF EXISTS banned_at,
+    DROP COLUMN IF EXISTS banned_until;
@@ -0,0 +1,43 @@
+-- a banned user keeps their chat_members row with is_banned set,
+-- the row is not a membership anymore. banned_until NULL means forever.
+UPDATE chat_members SET is_banned = FALSE WHERE is_banned IS NULL;
+ALTER TABLE chat_members ALTER COLUMN is_banned SET NOT NULL;
+
+ALTER TABLE chat_members
+    ADD COLUMN ban_reason TEXT DEFAULT NULL,
+    ADD COLUMN banned_by BIGINT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN banned_at TIMESTAMP DEFAULT NULL,
+    ADD COLUMN banned_until TIMESTAMP DEFAULT NULL;
+
+-- who kicked, banned or unbanned whom
+CREATE TABLE chat_moderation_log(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_user_id BIGINT DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+
+    action VARCHAR(16) NOT NULL,
+    reason TEXT DEFAULT NULL,
+    expires_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_chat_moderation_log_chat_id_id ON chat_moderation_log(chat_id, id);
+
+-- last line of defence: banned users can't write into msgs whatever the code path
+CREATE FUNCTION msgs_reject_banned() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM chat_members
+        WHERE chat_id = NEW.chat_id AND user_id = NEW.author_user_id AND is_banned
+    ) THEN
+        RAISE EXCEPTION 'user % is banned in chat %', NEW.author_user_id, NEW.chat_id
+            USING ERRCODE = 'insufficient_privilege';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER msgs_reject_banned BEFORE INSERT ON msgs
+    FOR EACH ROW EXECUTE FUNCTION msgs_reject_banned();
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS chats_touch_last_msg ON msgs;
+DROP FUNCTION IF EXISTS chats_touch_last_msg();
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS last_msg_id,
+    DROP COLUMN IF EXISTS last_activity_at;
@@ -0,0 +1,27 @@
+-- denormalized for the chat list: sorting a user's chats by activity must
+-- not look into msgs for every chat
+ALTER TABLE chats
+    ADD COLUMN last_msg_id BIGINT DEFAULT NULL,
+    ADD COLUMN last_activity_at TIMESTAMP NOT NULL DEFAULT current_timestamp;
+
+UPDATE chats c SET
+    last_msg_id = m.id,
+    last_activity_at = m.created_at
+FROM (
+    SELECT DISTINCT ON (chat_id) chat_id, id, created_at FROM msgs ORDER BY chat_id, id DESC
+) m
+WHERE m.chat_id = c.id;
+
+UPDATE chats SET last_activity_at = created_at WHERE last_msg_id IS NULL AND created_at IS NOT NULL;
+
+CREATE FUNCTION chats_touch_last_msg() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE chats SET last_msg_id = NEW.id, last_activity_at = NEW.created_at
+    WHERE id = NEW.chat_id AND (last_msg_id IS NULL OR last_msg_id < NEW.id);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER chats_touch_last_msg AFTER INSERT ON msgs
+    FOR EACH ROW EXECUTE FUNCTION chats_touch_last_msg();
@@ -0,0 +1,4 @@
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS avatar;
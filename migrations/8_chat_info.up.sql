@@ -0,0 +1,4 @@
+ALTER TABLE chats
+    ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN avatar VARCHAR(512) NOT NULL DEFAULT '';
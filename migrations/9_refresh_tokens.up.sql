@@ -0,0 +1,24 @@
+-- a session is one login, its refresh tokens form a family: every refresh
+-- rotates the token and presenting a rotated one again revokes the session
+CREATE TABLE auth_sessions(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    revoked_at TIMESTAMP DEFAULT NULL
+);
+CREATE INDEX idx_auth_sessions_user_id ON auth_sessions(user_id);
+
+-- only sha256 of a refresh token is stored
+CREATE TABLE refresh_tokens(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+
+    token_hash BYTEA NOT NULL UNIQUE,
+
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP DEFAULT NULL
+);
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);